@@ -0,0 +1,257 @@
+// Package admin serves an http.Handler for inspecting and managing the caches
+// and queues in a running process.
+//
+// Routes, relative to wherever the handler is mounted:
+//
+//	GET    /                           every registered cache and queue
+//	GET    /caches/{name}              size and stats for one cache
+//	POST   /caches/{name}/flush        uncache everything
+//	GET    /caches/{name}/keys/{key}   look up a key, without touching it
+//	DELETE /caches/{name}/keys/{key}   uncache a key
+//	GET    /queues/{name}              dedup size and inflight count
+//	POST   /queues/{name}/flush        empty the dedup cache
+//	GET    /queues/{name}/keys/{key}   check if a key is deduped
+//	DELETE /queues/{name}/keys/{key}   uncache a deduped key
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dustinevan/go-utils/cache"
+)
+
+// Cache is implemented by cache.ObjCache and cache.TimeoutCache
+type Cache interface {
+	Len() int
+	Stats() cache.Stats
+	Peek(k string) (interface{}, error)
+	Uncache(k string)
+	Flush()
+}
+
+// Queue is implemented by async.UniQueue
+type Queue interface {
+	Name() string
+	Len() int
+	Inflight() int
+	Check(s string) bool
+	UnCache(s string) bool
+	Flush()
+}
+
+type Registry struct {
+	mu     sync.RWMutex
+	caches map[string]Cache
+	queues map[string]Queue
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		caches: make(map[string]Cache),
+		queues: make(map[string]Queue),
+	}
+}
+
+// DefaultRegistry is used by the package level Register funcs and Handler
+var DefaultRegistry = NewRegistry()
+
+func RegisterCache(name string, c Cache) {
+	DefaultRegistry.RegisterCache(name, c)
+}
+
+func RegisterQueue(q Queue) {
+	DefaultRegistry.RegisterQueue(q)
+}
+
+func Handler() http.Handler {
+	return DefaultRegistry
+}
+
+// RegisterCache adds c under name, replacing anything already registered there
+func (r *Registry) RegisterCache(name string, c Cache) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.caches[name] = c
+}
+
+// RegisterQueue adds q under q.Name()
+func (r *Registry) RegisterQueue(q Queue) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queues[q.Name()] = q
+}
+
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.caches, name)
+	delete(r.queues, name)
+}
+
+type cacheInfo struct {
+	Name     string      `json:"name"`
+	Type     string      `json:"type"`
+	Size     int         `json:"size"`
+	HitRatio float64     `json:"hit_ratio"`
+	Stats    cache.Stats `json:"stats"`
+}
+
+type queueInfo struct {
+	Name string `json:"name"`
+	// Size is left out for queues that can't count their keys
+	Size     *int `json:"size,omitempty"`
+	Inflight int  `json:"inflight"`
+}
+
+func describeCache(name string, c Cache) cacheInfo {
+	s := c.Stats()
+	return cacheInfo{
+		Name:     name,
+		Type:     strings.TrimPrefix(fmt.Sprintf("%T", c), "*"),
+		Size:     c.Len(),
+		HitRatio: s.HitRatio(),
+		Stats:    s,
+	}
+}
+
+func describeQueue(q Queue) queueInfo {
+	info := queueInfo{Name: q.Name(), Inflight: q.Inflight()}
+	if n := q.Len(); n >= 0 {
+		info.Size = &n
+	}
+	return info
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(parts) == 1 && parts[0] == "" {
+		r.serveIndex(w, req)
+		return
+	}
+	if len(parts) < 2 {
+		http.NotFound(w, req)
+		return
+	}
+
+	r.mu.RLock()
+	c, isCache := r.caches[parts[1]]
+	q, isQueue := r.queues[parts[1]]
+	r.mu.RUnlock()
+
+	switch {
+	case parts[0] == "caches" && isCache:
+		serveCache(w, req, parts[1], c, parts[2:])
+	case parts[0] == "queues" && isQueue:
+		serveQueue(w, req, q, parts[2:])
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (r *Registry) serveIndex(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	r.mu.RLock()
+	caches := make([]cacheInfo, 0, len(r.caches))
+	for name, c := range r.caches {
+		caches = append(caches, describeCache(name, c))
+	}
+	queues := make([]queueInfo, 0, len(r.queues))
+	for _, q := range r.queues {
+		queues = append(queues, describeQueue(q))
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(caches, func(i, j int) bool { return caches[i].Name < caches[j].Name })
+	sort.Slice(queues, func(i, j int) bool { return queues[i].Name < queues[j].Name })
+	writeJSON(w, map[string]interface{}{"caches": caches, "queues": queues})
+}
+
+func serveCache(w http.ResponseWriter, req *http.Request, name string, c Cache, rest []string) {
+	switch {
+	case len(rest) == 0 && req.Method == http.MethodGet:
+		writeJSON(w, describeCache(name, c))
+	case len(rest) == 1 && rest[0] == "flush" && req.Method == http.MethodPost:
+		c.Flush()
+		w.WriteHeader(http.StatusNoContent)
+	case len(rest) >= 2 && rest[0] == "keys":
+		key := strings.Join(rest[1:], "/")
+		switch req.Method {
+		case http.MethodGet:
+			v, err := c.Peek(key)
+			if err == cache.NOT_FOUND {
+				http.NotFound(w, req)
+				return
+			}
+			if err == cache.ABSENT {
+				writeJSON(w, map[string]interface{}{"key": key, "absent": true})
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+			writeJSON(w, map[string]interface{}{"key": key, "value": printable(v)})
+		case http.MethodDelete:
+			c.Uncache(key)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func serveQueue(w http.ResponseWriter, req *http.Request, q Queue, rest []string) {
+	switch {
+	case len(rest) == 0 && req.Method == http.MethodGet:
+		writeJSON(w, describeQueue(q))
+	case len(rest) == 1 && rest[0] == "flush" && req.Method == http.MethodPost:
+		q.Flush()
+		w.WriteHeader(http.StatusNoContent)
+	case len(rest) >= 2 && rest[0] == "keys":
+		key := strings.Join(rest[1:], "/")
+		switch req.Method {
+		case http.MethodGet:
+			if !q.Check(key) {
+				http.NotFound(w, req)
+				return
+			}
+			writeJSON(w, map[string]interface{}{"key": key})
+		case http.MethodDelete:
+			if !q.UnCache(key) {
+				http.NotFound(w, req)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// printable returns v if it can be marshaled to json, otherwise its %v string
+func printable(v interface{}) interface{} {
+	if _, err := json.Marshal(v); err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return v
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,80 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dustinevan/go-utils/async"
+	"github.com/dustinevan/go-utils/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func do(h http.Handler, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestRegistry(t *testing.T) {
+	ctx, canc := context.WithCancel(context.Background())
+	defer canc()
+
+	objs := cache.NewObjCache(10)
+	defer objs.Close()
+	dedup := cache.NewTimeoutCache(10)
+	defer dedup.Close()
+	q := async.NewUniQueue("work", 10, 10, time.Minute, ctx)
+	filter, _ := cache.NewRotatingBloom(time.Hour, 4, 1000, 0.01)
+	bloom := async.NewUniQueue("bloom", 10, 10, time.Minute, ctx, async.WithDedupFilter(filter))
+
+	r := NewRegistry()
+	r.RegisterCache("objs", objs)
+	r.RegisterCache("dedup", dedup)
+	r.RegisterQueue(q)
+	r.RegisterQueue(bloom)
+
+	objs.Insert("user/1", map[string]string{"name": "dustin"}, time.Minute)
+	objs.Insert("user/2", "two", time.Minute)
+	dedup.Insert("a", time.Minute)
+	q.Insert("job1")
+
+	w := do(r, "GET", "/")
+	assert.Equal(t, http.StatusOK, w.Code, "index failed")
+	var index struct {
+		Caches []cacheInfo
+		Queues []queueInfo
+	}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &index), "index isn't json")
+	assert.Len(t, index.Caches, 2, "index didn't list every cache")
+	assert.Equal(t, "dedup", index.Caches[0].Name, "index isn't sorted")
+	assert.Equal(t, "cache.TimeoutCache", index.Caches[0].Type, "wrong cache type")
+	assert.Equal(t, 2, index.Caches[1].Size, "wrong cache size")
+	assert.Len(t, index.Queues, 2, "index didn't list every queue")
+	assert.Nil(t, index.Queues[0].Size, "a filtered queue has a size")
+	if assert.NotNil(t, index.Queues[1].Size, "queue size is missing") {
+		assert.Equal(t, 1, *index.Queues[1].Size, "wrong queue size")
+	}
+
+	w = do(r, "GET", "/caches/objs/keys/user/1")
+	assert.Equal(t, http.StatusOK, w.Code, "key lookup failed")
+	assert.Contains(t, w.Body.String(), `"dustin"`, "key lookup didn't return the value")
+	assert.Equal(t, http.StatusNotFound, do(r, "GET", "/caches/objs/keys/user/3").Code, "missing key was found")
+
+	assert.Equal(t, http.StatusNoContent, do(r, "DELETE", "/caches/objs/keys/user/1").Code, "delete failed")
+	assert.False(t, objs.Check("user/1"), "delete didn't uncache the key")
+
+	assert.Equal(t, http.StatusNoContent, do(r, "POST", "/caches/objs/flush").Code, "flush failed")
+	assert.Equal(t, 0, objs.Len(), "flush didn't empty the cache")
+
+	assert.Equal(t, http.StatusOK, do(r, "GET", "/queues/work/keys/job1").Code, "deduped key wasn't found")
+	assert.Equal(t, http.StatusNoContent, do(r, "DELETE", "/queues/work/keys/job1").Code, "queue delete failed")
+	assert.False(t, q.Check("job1"), "queue delete didn't uncache the key")
+	assert.Equal(t, http.StatusNotFound, do(r, "DELETE", "/queues/work/keys/job1").Code, "deleting a missing key succeeded")
+
+	assert.Equal(t, http.StatusNotFound, do(r, "GET", "/caches/nope").Code, "unknown cache was found")
+	assert.Equal(t, http.StatusMethodNotAllowed, do(r, "PUT", "/caches/objs/keys/a").Code, "wrong method was allowed")
+}
@@ -3,7 +3,6 @@ package async
 import (
 	"context"
 	"errors"
-	"log"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -14,6 +13,18 @@ import (
 var QFULL = errors.New("the channel is full, nothing can be inserted until consumers catch up")
 var UNIQUEUE_CLOSED = errors.New("this uniqueue is closed")
 
+type UniQueueOption func(q *UniQueue)
+
+// WithDedupFilter dedups with f instead of the exact dedup map, e.g. a
+// cache.RotatingBloom to keep memory bounded for huge key sets. maxdedup and
+// deduptime don't apply, f decides how long keys are remembered, and UnCache
+// is a no-op because filters can't forget a single key.
+func WithDedupFilter(f cache.DedupFilter) UniQueueOption {
+	return func(q *UniQueue) {
+		q.filter = f
+	}
+}
+
 type UniQueue struct {
 	// name is for logging purposes only
 	name string
@@ -29,6 +40,8 @@ type UniQueue struct {
 	// max size of the dedup cache
 	maxdedup int
 	dedup    map[string]int64
+	// replaces dedup when set
+	filter cache.DedupFilter
 
 	// track how many records are inflight, i.e deduped records in the channel
 	// this is used to keep the Insert func from blocking. Insert returns CHANFULL
@@ -47,7 +60,7 @@ type UniQueue struct {
 	closed int32
 }
 
-func NewUniQueue(name string, maxdedup, maxinflight int, deduptime time.Duration, parentCtx context.Context) *UniQueue {
+func NewUniQueue(name string, maxdedup, maxinflight int, deduptime time.Duration, parentCtx context.Context, opts ...UniQueueOption) *UniQueue {
 
 	t := &UniQueue{
 		name:        name,
@@ -59,6 +72,9 @@ func NewUniQueue(name string, maxdedup, maxinflight int, deduptime time.Duration
 		outgoing:    make(chan string, 8),
 		ctx:         parentCtx,
 	}
+	for _, opt := range opts {
+		opt(t)
+	}
 
 	// cache cleanup go routine. if ctx.Err, this closes and drains the channels
 	go func() {
@@ -95,23 +111,51 @@ func NewUniQueue(name string, maxdedup, maxinflight int, deduptime time.Duration
 	go func() {
 		<-t.ctx.Done()
 		t.mu.Lock()
-		t.closed = 1
+		atomic.StoreInt32(&t.closed, 1)
 		close(t.q)
 		drainString(t.q)
 		drainString(t.outgoing)
 		t.mu.Unlock()
 	}()
 
-	TickerLog(time.Second*30, parentCtx, func() {
-		log.Printf("%s UniQueue has %v records inflight", t.name, atomic.LoadInt32(&t.inflight))
-	})
-
 	return t
 }
 
+// Name returns the name the UniQueue was created with
+func (c *UniQueue) Name() string {
+	return c.name
+}
+
+// Inflight returns how many deduped records are waiting in the channel
+func (c *UniQueue) Inflight() int {
+	return int(atomic.LoadInt32(&c.inflight))
+}
+
+// Len returns how many keys are in the dedup cache, or -1 with a DedupFilter
+// since a filter can't count its keys
+func (c *UniQueue) Len() int {
+	if c.filter != nil {
+		return -1
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.dedup)
+}
+
+// Flush empties the dedup cache, records already inflight are unaffected
+func (c *UniQueue) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dedup = make(map[string]int64)
+	c.records = 0
+}
+
 // Checks if the value exists in the cache
 // most callers should call insert and read the error
 func (c *UniQueue) Check(s string) bool {
+	if c.filter != nil {
+		return c.filter.Test(s)
+	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	_, ok := c.dedup[s]
@@ -122,6 +166,18 @@ func (c *UniQueue) Check(s string) bool {
 // before writing to the internal inflight channel.
 func (c *UniQueue) Insert(s string) error {
 	// ok with race conditions here
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return UNIQUEUE_CLOSED
+	}
+
+	if c.filter != nil {
+		return c.insertFiltered(s)
+	}
+
+	// not ok with race conditions here, Flush swaps the dedup map
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// check again inside the lock.
 	if c.closed == 1 {
 		return UNIQUEUE_CLOSED
 	}
@@ -135,34 +191,55 @@ func (c *UniQueue) Insert(s string) error {
 		return cache.CACHEFULL
 	}
 
-	// not ok with race conditions here
+	if atomic.LoadInt32(&c.inflight) == int32(c.maxinflight) {
+		return QFULL
+	}
+
+	c.dedup[s] = time.Now().Add(c.deduptime).Unix()
+	c.records++
+
+	// because of the check above this shouldn't block
+	atomic.AddInt32(&c.inflight, 1)
+	c.q <- s
+
+	return nil
+}
+
+// insertFiltered is Insert when a DedupFilter replaces the dedup map
+func (c *UniQueue) insertFiltered(s string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	// check again inside the lock.
 	if c.closed == 1 {
 		return UNIQUEUE_CLOSED
 	}
 
+	// check inflight first so a full queue doesn't add s to the filter
 	if atomic.LoadInt32(&c.inflight) == int32(c.maxinflight) {
 		return QFULL
 	}
+	if c.filter.TestAndAdd(s) {
+		return cache.ALREADY_EXISTS
+	}
 
-	c.dedup[s] = time.Now().Add(c.deduptime).Unix()
-	c.records++
-
-	// because of the check above this shouldn't block
 	atomic.AddInt32(&c.inflight, 1)
 	c.q <- s
-
 	return nil
 }
 
-// Uncaches a specific dedup cache entry, which unblocks it from flowing through the channel
-func (c *UniQueue) UnCache(s string) {
+// Uncaches a specific dedup cache entry, which unblocks it from flowing through the channel.
+// It returns false if s wasn't cached, which is always the case with a DedupFilter.
+func (c *UniQueue) UnCache(s string) bool {
+	if c.filter != nil {
+		return false
+	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if _, ok := c.dedup[s]; !ok {
+		return false
+	}
 	delete(c.dedup, s)
 	c.records--
+	return true
 }
 
 // GetChan returns the output channel, used by n consumers to consume deduped records.
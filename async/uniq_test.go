@@ -2,6 +2,7 @@ package async
 
 import (
 	"context"
+	"strconv"
 	"testing"
 	"time"
 
@@ -16,8 +17,17 @@ func TestUniQueue_Check(t *testing.T) {
 	assert.Nil(t, ch1.Insert("a"), "first insert failed, three should work")
 	assert.True(t, ch1.Check("a"), "dedup failed to store first inserted string")
 	// wait for cachecleanup
-	ch1.UnCache("a")
+	assert.True(t, ch1.UnCache("a"), "cached key wasn't uncached")
 	assert.False(t, ch1.Check("d"), "dedup stored more inserts than allowed by maxdedup")
+
+	// uncaching a missing key doesn't free a slot
+	assert.False(t, ch1.UnCache("a"), "missing key was uncached")
+	assert.False(t, ch1.UnCache("x"), "missing key was uncached")
+	for _, s := range []string{"b", "c", "d"} {
+		assert.Nil(t, ch1.Insert(s), "insert failed")
+		<-ch1.GetChan()
+	}
+	assert.Equal(t, cache.CACHEFULL, ch1.Insert("e"), "missing keys uncached made room past maxdedup")
 	canc()
 	assert.False(t, ch1.Check("a"), "dedup failed to store first inserted string")
 }
@@ -94,11 +104,68 @@ func TestUniQueue_UnCache(t *testing.T) {
 	assert.Nil(t, ch1.Insert("a"), "first insert failed, three should work")
 	assert.True(t, ch1.Check("a"), "dedup failed to store first inserted string")
 	// wait for cachecleanup
-	ch1.UnCache("a")
+	assert.True(t, ch1.UnCache("a"), "cached key wasn't uncached")
 	assert.False(t, ch1.Check("d"), "dedup stored more inserts than allowed by maxdedup")
+
+	// uncaching a missing key doesn't free a slot
+	assert.False(t, ch1.UnCache("a"), "missing key was uncached")
+	assert.False(t, ch1.UnCache("x"), "missing key was uncached")
+	for _, s := range []string{"b", "c", "d"} {
+		assert.Nil(t, ch1.Insert(s), "insert failed")
+		<-ch1.GetChan()
+	}
+	assert.Equal(t, cache.CACHEFULL, ch1.Insert("e"), "missing keys uncached made room past maxdedup")
 	canc()
 }
 
 func TestUniQueue_Close(t *testing.T) {
 
 }
+
+func TestUniQueue_Flush(t *testing.T) {
+	ctx, canc := context.WithCancel(context.Background())
+	defer canc()
+	ch1 := NewUniQueue("test", 3, 3, time.Second, ctx)
+
+	assert.Equal(t, "test", ch1.Name(), "wrong name")
+	assert.Nil(t, ch1.Insert("a"), "first insert failed")
+	assert.Nil(t, ch1.Insert("b"), "second insert failed")
+	assert.Equal(t, 2, ch1.Len(), "wrong dedup size")
+	time.Sleep(time.Millisecond)
+	assert.Equal(t, 0, ch1.Inflight(), "records weren't moved to outgoing")
+
+	ch1.Flush()
+	assert.Equal(t, 0, ch1.Len(), "flush didn't empty the dedup cache")
+	assert.Nil(t, ch1.Insert("a"), "flushed key was still deduped")
+
+	// flushes racing inserts, run with -race
+	ch2 := NewUniQueue("test", 1000, 1000, time.Second, ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			ch2.Flush()
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		ch2.Insert(strconv.Itoa(i))
+	}
+	<-done
+}
+
+func TestUniQueue_DedupFilter(t *testing.T) {
+	ctx, canc := context.WithCancel(context.Background())
+	defer canc()
+	filter, err := cache.NewRotatingBloom(time.Hour, 4, 1000, 0.01)
+	assert.Nil(t, err, "new filter failed")
+	ch1 := NewUniQueue("test", 1, 2, time.Second, ctx, WithDedupFilter(filter))
+
+	assert.Nil(t, ch1.Insert("a"), "first insert failed")
+	assert.True(t, ch1.Check("a"), "filter didn't remember the insert")
+	assert.Equal(t, cache.ALREADY_EXISTS, ch1.Insert("a"), "dedup failed")
+	assert.Nil(t, ch1.Insert("b"), "maxdedup limited a filtered queue")
+	assert.Equal(t, -1, ch1.Len(), "a filtered queue counted its keys")
+	assert.Equal(t, "a", <-ch1.GetChan(), "wrong record out of the queue")
+	assert.False(t, ch1.UnCache("a"), "a filtered queue uncached a key")
+	assert.Equal(t, cache.ALREADY_EXISTS, ch1.Insert("a"), "uncache changed the filter")
+}
@@ -0,0 +1,197 @@
+package cache
+
+import (
+	"errors"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+)
+
+// FILTER_TOO_BIG is returned when a bloom filter sized for the requested items and
+// false positive rate needs more bits than its 32 bit hashes can address.
+var FILTER_TOO_BIG = errors.New("bloom filter would need more than 2^32 bits, use more buckets or a higher false positive rate")
+
+// A DedupFilter remembers keys for a window of time. TestAndAdd adds k and reports
+// whether it was already there. Probabilistic filters may report a key that was
+// never added, but never miss one that was.
+type DedupFilter interface {
+	Test(k string) bool
+	TestAndAdd(k string) bool
+}
+
+// RotatingBloom is a DedupFilter built from a ring of bloom filters, each covering
+// window/buckets of time. Keys are added to the newest filter and tested against
+// all of them. When a bucket's time is up the oldest filter is cleared and reused,
+// so a key is remembered for between window - window/buckets and window, and
+// memory stays fixed no matter how many keys pass through.
+type RotatingBloom struct {
+	filters []*bloom
+	bucket  time.Duration
+	current int
+	started time.Time
+	mu      sync.Mutex
+}
+
+// NewRotatingBloom sizes the filters so the whole ring holds expectedItems keys
+// per window with a false positive rate of about fpRate. Keys are assumed to
+// arrive at a steady rate, a burst bigger than a bucket's share raises the rate.
+// Each filter is limited to 2^32 bits (512MB), FILTER_TOO_BIG is returned if a
+// bucket's share of expectedItems at fpRate needs more.
+func NewRotatingBloom(window time.Duration, buckets, expectedItems int, fpRate float64) (*RotatingBloom, error) {
+	if buckets < 1 {
+		buckets = 1
+	}
+	if expectedItems < 1 {
+		expectedItems = 1
+	}
+	// every filter is tested, so each gets an equal share of the false positive rate
+	perBucket := int(math.Ceil(float64(expectedItems) / float64(buckets)))
+	m, k, err := bloomSize(perBucket, fpRate/float64(buckets))
+	if err != nil {
+		return nil, err
+	}
+	r := &RotatingBloom{
+		filters: make([]*bloom, buckets),
+		bucket:  window / time.Duration(buckets),
+		started: time.Now(),
+	}
+	for i := range r.filters {
+		r.filters[i] = newBloom(m, k)
+	}
+	return r, nil
+}
+
+// rotate clears filters whose bucket has passed. must be called inside the lock.
+func (r *RotatingBloom) rotate() {
+	if r.bucket <= 0 {
+		return
+	}
+	elapsed := int(time.Since(r.started) / r.bucket)
+	steps := elapsed - r.current
+	if steps <= 0 {
+		return
+	}
+	if steps > len(r.filters) {
+		steps = len(r.filters)
+	}
+	for i := 1; i <= steps; i++ {
+		r.filters[(r.current+i)%len(r.filters)].reset()
+	}
+	r.current = elapsed
+}
+
+func (r *RotatingBloom) Test(k string) bool {
+	h1, h2 := bloomHash(k)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rotate()
+	return r.test(h1, h2)
+}
+
+func (r *RotatingBloom) test(h1, h2 uint32) bool {
+	for _, f := range r.filters {
+		if f.test(h1, h2) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RotatingBloom) TestAndAdd(k string) bool {
+	h1, h2 := bloomHash(k)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rotate()
+	if r.test(h1, h2) {
+		return true
+	}
+	r.filters[r.current%len(r.filters)].add(h1, h2)
+	return false
+}
+
+type bloom struct {
+	bits []uint64
+	m    uint32
+	k    uint32
+}
+
+// bloomSize returns the number of bits and hashes for n items at false positive rate p
+func bloomSize(n int, p float64) (uint32, uint32, error) {
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+	m := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	if m > math.MaxUint32 {
+		return 0, 0, FILTER_TOO_BIG
+	}
+	k := math.Max(1, math.Round(m/float64(n)*math.Ln2))
+	return uint32(m), uint32(k), nil
+}
+
+func newBloom(m, k uint32) *bloom {
+	return &bloom{
+		bits: make([]uint64, (uint64(m)+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+// bloomHash splits a 64 bit hash into the two hashes used for double hashing
+func bloomHash(k string) (uint32, uint32) {
+	h := fnv.New64a()
+	h.Write([]byte(k))
+	// fnv's bits are poorly mixed for short, similar keys, finish with murmur3's fmix64
+	sum := h.Sum64()
+	sum ^= sum >> 33
+	sum *= 0xff51afd7ed558ccd
+	sum ^= sum >> 33
+	sum *= 0xc4ceb9fe1a85ec53
+	sum ^= sum >> 33
+	return uint32(sum), uint32(sum>>32) | 1
+}
+
+func (b *bloom) add(h1, h2 uint32) {
+	for i := uint32(0); i < b.k; i++ {
+		bit := (h1 + i*h2) % b.m
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (b *bloom) test(h1, h2 uint32) bool {
+	for i := uint32(0); i < b.k; i++ {
+		bit := (h1 + i*h2) % b.m
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *bloom) reset() {
+	for i := range b.bits {
+		b.bits[i] = 0
+	}
+}
+
+// TimeoutFilter is an exact DedupFilter backed by a TimeoutCache. Use it when
+// false positives aren't acceptable and the keys fit in memory.
+type TimeoutFilter struct {
+	c      *TimeoutCache
+	window time.Duration
+}
+
+func NewTimeoutFilter(c *TimeoutCache, window time.Duration) *TimeoutFilter {
+	return &TimeoutFilter{c: c, window: window}
+}
+
+func (f *TimeoutFilter) Test(k string) bool {
+	return f.c.Check(k)
+}
+
+// TestAndAdd reports a full or closed cache as a duplicate, so nothing gets through
+// that the filter can't remember.
+func (f *TimeoutFilter) TestAndAdd(k string) bool {
+	loaded, err := f.c.InsertIfAbsent(k, f.window)
+	return loaded || err != nil
+}
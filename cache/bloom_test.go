@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotatingBloom(t *testing.T) {
+	r, err := NewRotatingBloom(time.Hour, 4, 10000, 0.01)
+	assert.Nil(t, err, "new filter failed")
+
+	assert.False(t, r.TestAndAdd("a"), "new key reported as seen")
+	assert.True(t, r.TestAndAdd("a"), "added key not reported as seen")
+	assert.True(t, r.Test("a"), "added key not reported as seen")
+	assert.False(t, r.Test("b"), "test added a key")
+	assert.False(t, r.TestAndAdd("b"), "new key reported as seen")
+
+	// a steady rate puts a quarter of the window's keys in each bucket
+	fp := 0
+	for i := 0; i < 2500; i++ {
+		r.TestAndAdd(fmt.Sprint("key", i))
+	}
+	for i := 0; i < 10000; i++ {
+		if r.Test(fmt.Sprint("other", i)) {
+			fp++
+		}
+	}
+	assert.True(t, fp < 200, "false positive rate too high")
+}
+
+func TestRotatingBloomExpiration(t *testing.T) {
+	r, _ := NewRotatingBloom(time.Millisecond*40, 4, 100, 0.01)
+
+	r.TestAndAdd("a")
+	time.Sleep(time.Millisecond * 20)
+	assert.True(t, r.Test("a"), "key forgotten inside the window")
+	time.Sleep(time.Millisecond * 30)
+	assert.False(t, r.Test("a"), "key remembered after the window")
+}
+
+func TestRotatingBloomSize(t *testing.T) {
+	_, err := NewRotatingBloom(time.Hour, 1, 1e9, 1e-9)
+	assert.Equal(t, FILTER_TOO_BIG, err, "filter bigger than 2^32 bits wasn't rejected")
+
+	m, k, err := bloomSize(1e8, 0.01)
+	assert.Nil(t, err, "filter under 2^32 bits was rejected")
+	assert.True(t, m > 9e8, "filter size overflowed")
+	assert.Equal(t, uint32(7), k, "wrong number of hashes")
+}
+
+func TestTimeoutFilter(t *testing.T) {
+	c := NewTimeoutCache(1)
+	defer c.Close()
+	f := NewTimeoutFilter(c, time.Minute)
+
+	assert.False(t, f.TestAndAdd("a"), "new key reported as seen")
+	assert.True(t, f.TestAndAdd("a"), "added key not reported as seen")
+	assert.True(t, f.TestAndAdd("b"), "full cache let a key through")
+}
@@ -0,0 +1,366 @@
+package cache
+
+import (
+	"container/heap"
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+type WithOptions interface {
+	SetScanRate(duration time.Duration)
+	SetSliding(maxlifetime time.Duration)
+	SetContext(ctx context.Context)
+}
+
+type CacheOption func(cache WithOptions)
+
+func ScanRate(duration time.Duration) CacheOption {
+	return func(cache WithOptions) {
+		cache.SetScanRate(duration)
+	}
+}
+
+// WithContext ties the cache's lifetime to ctx. When ctx is done the cache
+// is closed, the same as calling Close.
+func WithContext(ctx context.Context) CacheOption {
+	return func(cache WithOptions) {
+		cache.SetContext(ctx)
+	}
+}
+
+// Sliding switches the cache to access-extended expiration. Every successful
+// Get or Check pushes the entry's expiration out by the ttl it was inserted with.
+// If maxlifetime > 0 an entry never lives longer than maxlifetime after its insert,
+// no matter how often it's read.
+func Sliding(maxlifetime time.Duration) CacheOption {
+	return func(cache WithOptions) {
+		cache.SetSliding(maxlifetime)
+	}
+}
+
+type val struct {
+	t time.Time
+	v interface{}
+
+	// k and idx place the entry in the expiry heap
+	k   string
+	idx int
+
+	// ttl and created are only needed for sliding expiration
+	ttl     time.Duration
+	created time.Time
+
+	cost int64
+	tags []string
+
+	// absent marks a negative entry, the key is known to be missing
+	absent bool
+}
+
+// extend moves the expiration to now + ttl, capped at created + maxlifetime
+func (e *val) extend(now time.Time, maxlifetime time.Duration) {
+	exp := now.Add(e.ttl)
+	if maxlifetime > 0 {
+		limit := e.created.Add(maxlifetime)
+		if exp.After(limit) {
+			exp = limit
+		}
+	}
+	e.t = exp
+}
+
+// core is the map, accounting, expiration and lifecycle shared by TimeoutCache
+// and ObjCache. Every write goes through put and every delete through remove,
+// so records and cost are always exact.
+type core struct {
+	c          map[string]*val
+	expiry     expiryHeap
+	records    int
+	maxrecords int
+	mu         sync.RWMutex
+	scanRate   time.Duration
+
+	sliding     bool
+	maxlifetime time.Duration
+
+	parentCtx context.Context
+	ctx       context.Context
+	canc      context.CancelFunc
+	sweeper   *sweeper
+
+	counters counters
+
+	cost    int64
+	maxcost int64
+	costfn  CostFn
+
+	// tags maps each tag to the keys inserted with it, prefixes holds every
+	// key for InvalidatePrefix.
+	tags     map[string]map[string]struct{}
+	prefixes *radix
+
+	jitter float64
+}
+
+func (h *core) init(maxrecords int) {
+	if maxrecords < 1 {
+		maxrecords = 1
+	}
+	h.c = make(map[string]*val)
+	h.maxrecords = maxrecords
+	h.scanRate = time.Second * 30
+	h.parentCtx = context.Background()
+	h.costfn = DefaultCost
+	h.tags = make(map[string]map[string]struct{})
+	h.prefixes = &radix{}
+}
+
+// start is called after options are applied
+func (h *core) start() {
+	h.ctx, h.canc = context.WithCancel(h.parentCtx)
+	h.sweeper = newSweeper(h.ctx, h.getScanRate)
+	h.sweeper.run(h.sweep)
+}
+
+// SetScanRate changes how often expired entries are swept. It can be called
+// on a running cache.
+func (h *core) SetScanRate(duration time.Duration) {
+	h.mu.Lock()
+	h.scanRate = duration
+	h.mu.Unlock()
+	if h.sweeper != nil {
+		h.sweeper.reschedule()
+	}
+}
+
+func (h *core) getScanRate() time.Duration {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.scanRate
+}
+
+func (h *core) SetContext(ctx context.Context) {
+	h.parentCtx = ctx
+}
+
+func (h *core) SetSliding(maxlifetime time.Duration) {
+	h.sliding = true
+	h.maxlifetime = maxlifetime
+}
+
+// Close stops the sweeper goroutine. After Close, writes and Get return CACHE_CLOSED
+// and Check returns false.
+func (h *core) Close() {
+	h.canc()
+	h.sweeper.wait()
+}
+
+func (h *core) closed() bool {
+	return h.ctx.Err() != nil
+}
+
+func (h *core) newVal(v interface{}, ttl time.Duration, tags []string) *val {
+	if h.jitter > 0 {
+		ttl += time.Duration(rand.Float64() * h.jitter * float64(ttl))
+	}
+	now := time.Now()
+	e := &val{v: v, ttl: ttl, created: now, cost: h.costfn(v), tags: tags}
+	e.extend(now, h.maxlifetime)
+	return e
+}
+
+// Check reports whether a value is cached at k. A negative entry, see
+// ObjCache.SetAbsent, counts as a hit but Check returns false for it.
+func (h *core) Check(k string) bool {
+	if h.closed() {
+		return false
+	}
+	h.mu.Lock()
+	v, ok := h.live(k)
+	if ok {
+		h.touch(v)
+	}
+	h.mu.Unlock()
+	h.counters.hit(ok)
+	return ok && !v.absent
+}
+
+func (h *core) get(k string) (interface{}, error) {
+	if h.closed() {
+		return nil, CACHE_CLOSED
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	v, ok := h.live(k)
+	h.counters.hit(ok)
+	if !ok {
+		return nil, NOT_FOUND
+	}
+	h.touch(v)
+	if v.absent {
+		return nil, ABSENT
+	}
+	return v.v, nil
+}
+
+// insert stores e only if k isn't cached, otherwise it returns ALREADY_EXISTS
+func (h *core) insert(k string, e *val) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed() {
+		return CACHE_CLOSED
+	}
+	if _, ok := h.live(k); ok {
+		return ALREADY_EXISTS
+	}
+	err := h.put(k, e)
+	h.counters.insert(err)
+	return err
+}
+
+// set stores e whether or not k is cached
+func (h *core) set(k string, e *val) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed() {
+		return CACHE_CLOSED
+	}
+	err := h.put(k, e)
+	h.counters.insert(err)
+	return err
+}
+
+// insertIfAbsent stores e if k isn't cached. It returns the cached value and
+// whether it was already there.
+func (h *core) insertIfAbsent(k string, e *val) (interface{}, bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed() {
+		return nil, false, CACHE_CLOSED
+	}
+	if old, ok := h.live(k); ok {
+		return old.v, true, nil
+	}
+	err := h.put(k, e)
+	h.counters.insert(err)
+	if err != nil {
+		return nil, false, err
+	}
+	return e.v, false, nil
+}
+
+// replace stores e only if k is cached, otherwise it returns NOT_FOUND
+func (h *core) replace(k string, e *val) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed() {
+		return CACHE_CLOSED
+	}
+	if _, ok := h.live(k); !ok {
+		return NOT_FOUND
+	}
+	err := h.put(k, e)
+	h.counters.insert(err)
+	return err
+}
+
+// compareAndSwap stores e only if the value cached at k == old. A negative
+// entry holds no value, so it never matches and ABSENT is returned.
+func (h *core) compareAndSwap(k string, old interface{}, e *val) (bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed() {
+		return false, CACHE_CLOSED
+	}
+	cur, ok := h.live(k)
+	if !ok {
+		return false, NOT_FOUND
+	}
+	if cur.absent {
+		return false, ABSENT
+	}
+	if cur.v != old {
+		return false, nil
+	}
+	e.tags = cur.tags
+	err := h.put(k, e)
+	h.counters.insert(err)
+	return err == nil, err
+}
+
+// put stores e at k, checking capacity and keeping records and cost correct.
+// must be called inside the lock.
+func (h *core) put(k string, e *val) error {
+	old, exists := h.c[k]
+	if h.maxcost > 0 {
+		if e.cost > h.maxcost {
+			return CACHEFULL
+		}
+		total := h.cost + e.cost
+		if exists {
+			total -= old.cost
+		}
+		var records int
+		if !exists && len(h.c) >= h.maxrecords {
+			records = len(h.c) - h.maxrecords + 1
+		}
+		if total > h.maxcost || records > 0 {
+			h.evict(total-h.maxcost, records, k)
+		}
+	} else if !exists && len(h.c) >= h.maxrecords {
+		return CACHEFULL
+	}
+
+	if exists {
+		h.cost -= old.cost
+		h.untag(k, old.tags)
+		heap.Remove(&h.expiry, old.idx)
+	} else {
+		h.records++
+		h.prefixes.insert(k)
+	}
+	e.k = k
+	h.c[k] = e
+	heap.Push(&h.expiry, e)
+	h.cost += e.cost
+	h.tag(k, e.tags)
+	return nil
+}
+
+// remove deletes k and its accounting. must be called inside the lock.
+func (h *core) remove(k string) bool {
+	v, ok := h.c[k]
+	if !ok {
+		return false
+	}
+	delete(h.c, k)
+	heap.Remove(&h.expiry, v.idx)
+	h.records--
+	h.cost -= v.cost
+	h.untag(k, v.tags)
+	h.prefixes.delete(k)
+	return true
+}
+
+func (h *core) Uncache(s string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(s)
+}
+
+func (h *core) UncacheMany(s []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, k := range s {
+		h.remove(k)
+	}
+}
+
+func (h *core) Stats() Stats {
+	h.mu.RLock()
+	size := len(h.c)
+	h.mu.RUnlock()
+	return h.counters.stats(size)
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"container/heap"
+	"sync/atomic"
+	"time"
+)
+
+// A CostFn estimates what a value costs to keep in the cache, usually its size in bytes.
+type CostFn func(v interface{}) int64
+
+// DefaultCost is the length of []byte and string values. Everything else costs 1.
+func DefaultCost(v interface{}) int64 {
+	switch t := v.(type) {
+	case []byte:
+		return int64(len(t))
+	case string:
+		return int64(len(t))
+	}
+	return 1
+}
+
+// MaxCost bounds a cache by the total cost of its entries as well as the number
+// of records. When an insert would go over maxcost or maxrecords, entries closest
+// to expiring are evicted to make room instead of the insert failing with CACHEFULL.
+// TimeoutCache entries have no value, so each costs 1.
+func MaxCost(maxcost int64) CacheOption {
+	return func(cache WithOptions) {
+		if c, ok := cache.(costOptions); ok {
+			c.setMaxCost(maxcost)
+		}
+	}
+}
+
+// WithCostFn replaces DefaultCost as the cost of cached values.
+func WithCostFn(fn CostFn) CacheOption {
+	return func(cache WithOptions) {
+		if c, ok := cache.(costOptions); ok {
+			c.setCostFn(fn)
+		}
+	}
+}
+
+type costOptions interface {
+	setMaxCost(maxcost int64)
+	setCostFn(fn CostFn)
+}
+
+func (h *core) setMaxCost(maxcost int64) {
+	h.maxcost = maxcost
+}
+
+func (h *core) setCostFn(fn CostFn) {
+	h.costfn = fn
+}
+
+// InsertWithCost is Insert with an explicit cost rather than one from the cache's CostFn.
+func (h *ObjCache) InsertWithCost(s string, v interface{}, cost int64, duration time.Duration, tags ...string) error {
+	e := h.newVal(v, duration, tags)
+	e.cost = cost
+	return h.insert(s, e)
+}
+
+// Cost returns the total cost of the entries in the cache
+func (h *core) Cost() int64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.cost
+}
+
+// evict removes entries, soonest to expire first, until at least amount cost
+// and records entries have been freed. skip is never evicted. must be called
+// inside the lock.
+func (h *core) evict(amount int64, records int, skip string) {
+	var freed int64
+	var removed int
+	var skipped *val
+	for (freed < amount || removed < records) && len(h.expiry) > 0 {
+		v := h.expiry[0]
+		if v.k == skip {
+			skipped = heap.Pop(&h.expiry).(*val)
+			continue
+		}
+		freed += v.cost
+		removed++
+		h.remove(v.k)
+		atomic.AddInt64(&h.counters.evictions, 1)
+	}
+	if skipped != nil {
+		heap.Push(&h.expiry, skipped)
+	}
+}
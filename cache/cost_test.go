@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultCost(t *testing.T) {
+	assert.Equal(t, int64(5), DefaultCost([]byte("hello")), "wrong cost for []byte")
+	assert.Equal(t, int64(3), DefaultCost("abc"), "wrong cost for string")
+	assert.Equal(t, int64(1), DefaultCost(42), "wrong cost for other values")
+}
+
+func TestMaxCost(t *testing.T) {
+	c := NewObjCache(10, MaxCost(10))
+	defer c.Close()
+
+	assert.Nil(t, c.Insert("a", "aaaa", time.Second), "insert under max cost failed")
+	assert.Nil(t, c.Insert("b", "bbbb", time.Second*2), "insert under max cost failed")
+	assert.Equal(t, int64(8), c.Cost(), "cost accounting is wrong")
+
+	// a expires first so it's evicted to make room
+	assert.Nil(t, c.Insert("c", "cccc", time.Second*3), "insert over max cost didn't evict")
+	assert.False(t, c.Check("a"), "the entry closest to expiring wasn't evicted")
+	assert.True(t, c.Check("b"), "evicted more than needed")
+	assert.True(t, c.Check("c"), "inserted value is missing")
+	assert.Equal(t, int64(8), c.Cost(), "cost accounting is wrong after eviction")
+	assert.Equal(t, 2, c.records, "record accounting is wrong after eviction")
+
+	assert.Equal(t, CACHEFULL, c.Insert("d", "ddddddddddd", time.Second), "value larger than max cost was inserted")
+}
+
+func TestMaxCostAndRecords(t *testing.T) {
+	c := NewObjCache(2, MaxCost(100))
+	defer c.Close()
+
+	assert.Nil(t, c.Insert("a", "a", time.Second), "insert under both limits failed")
+	assert.Nil(t, c.Insert("b", "b", time.Second*2), "insert under both limits failed")
+	assert.Nil(t, c.Insert("c", "c", time.Second*3), "insert over maxrecords didn't evict")
+	assert.Equal(t, 2, c.records, "maxrecords was ignored in a cost bounded cache")
+	assert.False(t, c.Check("a"), "the entry closest to expiring wasn't evicted")
+	assert.True(t, c.Check("b"), "evicted more than needed")
+	assert.Equal(t, int64(2), c.Cost(), "cost accounting is wrong after eviction")
+
+	assert.Nil(t, c.Set("b", "bb", time.Second*2), "overwriting an entry at maxrecords failed")
+	assert.Equal(t, 2, c.records, "overwriting an entry evicted another")
+}
+
+func TestInsertWithCost(t *testing.T) {
+	c := NewObjCache(10, MaxCost(100))
+	defer c.Close()
+
+	assert.Nil(t, c.InsertWithCost("a", 1, 60, time.Second), "insert with cost failed")
+	assert.Equal(t, ALREADY_EXISTS, c.InsertWithCost("a", 1, 30, time.Second), "insert with cost overwrote a value")
+	assert.Equal(t, int64(60), c.Cost(), "failed insert changed the cost")
+	assert.Nil(t, c.Set("a", "thirty bytes of string data...", time.Second), "set failed")
+	assert.Equal(t, int64(30), c.Cost(), "set didn't replace the old cost")
+	assert.Nil(t, c.InsertWithCost("b", 2, 70, time.Second), "insert with cost failed")
+	assert.Equal(t, int64(100), c.Cost(), "cost accounting is wrong")
+
+	c.Uncache("a")
+	assert.Equal(t, int64(70), c.Cost(), "uncache didn't release cost")
+	c.Uncache("a")
+	assert.Equal(t, int64(70), c.Cost(), "uncache of a missing key changed the cost")
+	assert.Equal(t, 1, c.records, "uncache of a missing key changed the record count")
+}
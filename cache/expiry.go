@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"container/heap"
+	"sync/atomic"
+	"time"
+)
+
+// sweepBatch bounds how many entries the sweeper expires per lock hold,
+// so readers never wait on a sweep of the whole cache.
+const sweepBatch = 1000
+
+// expiryHeap is a min-heap of entries ordered by expiration. Each val keeps
+// its index so sliding expiration and deletes are O(log n).
+type expiryHeap []*val
+
+func (e expiryHeap) Len() int           { return len(e) }
+func (e expiryHeap) Less(i, j int) bool { return e[i].t.Before(e[j].t) }
+func (e expiryHeap) Swap(i, j int) {
+	e[i], e[j] = e[j], e[i]
+	e[i].idx = i
+	e[j].idx = j
+}
+
+func (e *expiryHeap) Push(x interface{}) {
+	v := x.(*val)
+	v.idx = len(*e)
+	*e = append(*e, v)
+}
+
+func (e *expiryHeap) Pop() interface{} {
+	old := *e
+	n := len(old)
+	v := old[n-1]
+	old[n-1] = nil
+	v.idx = -1
+	*e = old[:n-1]
+	return v
+}
+
+// touch extends an unexpired entry when the cache is in sliding mode.
+// must be called inside the lock.
+func (h *core) touch(v *val) {
+	if !h.sliding {
+		return
+	}
+	now := time.Now()
+	if v.t.Before(now) {
+		return
+	}
+	v.extend(now, h.maxlifetime)
+	heap.Fix(&h.expiry, v.idx)
+}
+
+// live returns the entry at k if it hasn't expired. An expired entry is
+// removed on the spot rather than waiting for the sweeper. must be called
+// inside the write lock.
+func (h *core) live(k string) (*val, bool) {
+	v, ok := h.c[k]
+	if !ok {
+		return nil, false
+	}
+	if v.t.Before(time.Now()) {
+		h.remove(k)
+		atomic.AddInt64(&h.counters.expirations, 1)
+		return nil, false
+	}
+	return v, true
+}
+
+// sweep expires entries from the top of the heap, sweepBatch at a time,
+// releasing the lock between batches.
+func (h *core) sweep() {
+	for {
+		h.mu.Lock()
+		n := 0
+		now := time.Now()
+		for n < sweepBatch && len(h.expiry) > 0 && h.expiry[0].t.Before(now) {
+			h.remove(h.expiry[0].k)
+			n++
+		}
+		h.mu.Unlock()
+		atomic.AddInt64(&h.counters.expirations, int64(n))
+		if n < sweepBatch {
+			return
+		}
+	}
+}
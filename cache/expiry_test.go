@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiredIsMiss(t *testing.T) {
+	c := NewObjCache(10, ScanRate(time.Hour))
+	defer c.Close()
+
+	c.Insert("a", 1, time.Millisecond)
+	c.Insert("b", 2, time.Millisecond)
+	time.Sleep(time.Millisecond * 5)
+
+	assert.False(t, c.Check("a"), "check returned true for an expired key the sweeper hasn't reached")
+	_, err := c.Get("b")
+	assert.Equal(t, NOT_FOUND, err, "get returned an expired value")
+	assert.Nil(t, c.Insert("b", 3, time.Minute), "insert over an expired key failed")
+	assert.Equal(t, 1, c.records, "expired keys weren't removed on access")
+	assert.Equal(t, int64(2), c.Stats().Expirations, "expirations on access weren't counted")
+}
+
+func TestTimeoutCache_ExpiredIsMiss(t *testing.T) {
+	c := NewTimeoutCache(10, ScanRate(time.Hour))
+	defer c.Close()
+
+	c.Insert("a", time.Millisecond)
+	time.Sleep(time.Millisecond * 5)
+
+	assert.False(t, c.Check("a"), "check returned true for an expired key the sweeper hasn't reached")
+	assert.Nil(t, c.Insert("a", time.Minute), "insert over an expired key failed")
+}
+
+func TestSweepBatches(t *testing.T) {
+	n := sweepBatch*2 + 10
+	c := NewTimeoutCache(n+1, ScanRate(time.Hour))
+	defer c.Close()
+
+	for i := 0; i < n; i++ {
+		c.Insert(fmt.Sprint(i), time.Millisecond)
+	}
+	c.Insert("keep", time.Minute)
+	time.Sleep(time.Millisecond * 5)
+
+	c.sweep()
+	assert.Equal(t, 1, c.Len(), "sweep didn't expire every batch")
+	assert.Len(t, c.expiry, 1, "heap out of sync with the map")
+	assert.True(t, c.Check("keep"), "sweep removed an unexpired key")
+}
+
+func TestExpiryHeapOrder(t *testing.T) {
+	c := NewObjCache(10, ScanRate(time.Hour), Sliding(0))
+	defer c.Close()
+
+	c.Insert("a", 1, time.Millisecond*10)
+	c.Insert("b", 2, time.Millisecond*20)
+	c.Insert("c", 3, time.Millisecond*30)
+	assert.Equal(t, "a", c.expiry[0].k, "heap isn't ordered by expiration")
+
+	// reading a pushes its expiration past b's
+	time.Sleep(time.Millisecond * 15)
+	c.Get("a")
+	assert.Equal(t, "b", c.expiry[0].k, "sliding expiration didn't fix the heap")
+
+	c.Uncache("b")
+	c.Set("c", 4, time.Millisecond)
+	assert.Equal(t, "c", c.expiry[0].k, "overwrite didn't fix the heap")
+	for i, v := range c.expiry {
+		assert.Equal(t, i, v.idx, "heap index out of sync")
+	}
+}
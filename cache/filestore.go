@@ -0,0 +1,362 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var STORE_CLOSED = errors.New("this store is closed")
+var STORE_BROKEN = errors.New("a failed write couldn't be undone, this store no longer accepts writes")
+
+// A Store is a second cache tier, usually bigger and slower than ObjCache.
+// Get returns NOT_FOUND for missing or expired keys.
+type Store interface {
+	Get(k string) ([]byte, time.Time, error)
+	Set(k string, b []byte, expires time.Time) error
+	Delete(k string) error
+	Close() error
+}
+
+const (
+	opSet byte = iota
+	opDelete
+)
+
+// record header: op, expires unix nano, key length, value length
+const headerSize = 1 + 8 + 4 + 4
+
+type fileEntry struct {
+	off     int64 // offset of the value
+	size    int
+	expires time.Time
+}
+
+// FileStore is a Store kept in a single append only log file. An in-memory index
+// maps each key to the offset of its newest value. On open the log is replayed to
+// rebuild the index, and a torn write at the end of the file is truncated away. A
+// corrupt record anywhere else fails the open with a *CorruptLogError.
+// Overwritten, deleted and expired records are dropped by Compact, which runs
+// automatically once they take up more than half the file.
+type FileStore struct {
+	path string
+	f    *os.File
+	size int64
+	dead int64
+	// set when a torn record couldn't be cut off the end of the log
+	broken bool
+
+	index map[string]fileEntry
+	mu    sync.RWMutex
+}
+
+func OpenFileStore(path string) (*FileStore, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileStore{
+		path:  path,
+		f:     f,
+		index: make(map[string]fileEntry),
+	}
+	err = s.replay()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) replay() error {
+	info, err := s.f.Stat()
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(s.f)
+	var off int64
+	for {
+		op, expires, k, v, n, err := readRecord(r, info.Size()-off)
+		if err == io.EOF {
+			break
+		}
+		if err == io.ErrUnexpectedEOF {
+			// the last record runs past the end of the file, it's a torn write
+			break
+		}
+		if err != nil {
+			if off+n < info.Size() {
+				// records after this one can't be trusted to start where this one ends,
+				// leave the file alone rather than throw them away
+				return &CorruptLogError{Path: s.path, Offset: off, Size: info.Size(), Err: err}
+			}
+			// a bad record that ends the file is a torn write too
+			break
+		}
+		if old, ok := s.index[k]; ok {
+			s.dead += int64(headerSize + len(k) + old.size + 4)
+		}
+		switch op {
+		case opSet:
+			s.index[k] = fileEntry{off: off + headerSize + int64(len(k)), size: len(v), expires: expires}
+		case opDelete:
+			delete(s.index, k)
+			s.dead += n
+		}
+		off += n
+	}
+	if off < info.Size() {
+		log.Printf("cache: dropping %d bytes at the end of %s that don't hold a whole record", info.Size()-off, s.path)
+	}
+	s.size = off
+	err = s.f.Truncate(off)
+	if err != nil {
+		return err
+	}
+	_, err = s.f.Seek(off, io.SeekStart)
+	return err
+}
+
+// CorruptLogError is returned by OpenFileStore when a record in the middle of
+// the log fails its checksum. The file is left as it is.
+type CorruptLogError struct {
+	Path   string
+	Offset int64
+	Size   int64
+	Err    error
+}
+
+func (e *CorruptLogError) Error() string {
+	return fmt.Sprintf("filestore: %s: corrupt record at offset %d of %d: %s", e.Path, e.Offset, e.Size, e.Err)
+}
+
+func (e *CorruptLogError) Unwrap() error {
+	return e.Err
+}
+
+// readRecord reads the next record, which must fit in the remaining bytes of the
+// file. A record that doesn't fit returns io.ErrUnexpectedEOF before anything is
+// allocated for it, so a bad length can't cause a huge allocation. A record that
+// fails its checksum still returns its length in n.
+func readRecord(r io.Reader, remaining int64) (op byte, expires time.Time, k string, v []byte, n int64, err error) {
+	var hdr [headerSize]byte
+	_, err = io.ReadFull(r, hdr[:])
+	if err != nil {
+		return
+	}
+	op = hdr[0]
+	expires = time.Unix(0, int64(binary.BigEndian.Uint64(hdr[1:9])))
+	klen := int64(binary.BigEndian.Uint32(hdr[9:13]))
+	vlen := int64(binary.BigEndian.Uint32(hdr[13:17]))
+	n = headerSize + klen + vlen + 4
+	if n > remaining {
+		err = io.ErrUnexpectedEOF
+		return
+	}
+	body := make([]byte, klen+vlen+4)
+	_, err = io.ReadFull(r, body)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return
+	}
+	data := body[:klen+vlen]
+	sum := binary.BigEndian.Uint32(body[klen+vlen:])
+	crc := crc32.NewIEEE()
+	crc.Write(hdr[:])
+	crc.Write(data)
+	if crc.Sum32() != sum {
+		err = errors.New("record checksum mismatch")
+		return
+	}
+	k = string(data[:klen])
+	v = data[klen:]
+	return
+}
+
+func encodeRecord(op byte, k string, v []byte, expires time.Time) []byte {
+	b := make([]byte, headerSize+len(k)+len(v)+4)
+	b[0] = op
+	binary.BigEndian.PutUint64(b[1:9], uint64(expires.UnixNano()))
+	binary.BigEndian.PutUint32(b[9:13], uint32(len(k)))
+	binary.BigEndian.PutUint32(b[13:17], uint32(len(v)))
+	copy(b[headerSize:], k)
+	copy(b[headerSize+len(k):], v)
+	end := len(b) - 4
+	binary.BigEndian.PutUint32(b[end:], crc32.ChecksumIEEE(b[:end]))
+	return b
+}
+
+func (s *FileStore) Get(k string) ([]byte, time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.f == nil {
+		return nil, time.Time{}, STORE_CLOSED
+	}
+	e, ok := s.index[k]
+	if !ok || e.expires.Before(time.Now()) {
+		return nil, time.Time{}, NOT_FOUND
+	}
+	b := make([]byte, e.size)
+	_, err := s.f.ReadAt(b, e.off)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return b, e.expires, nil
+}
+
+func (s *FileStore) Set(k string, b []byte, expires time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.append(opSet, k, b, expires)
+}
+
+func (s *FileStore) Delete(k string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.index[k]; !ok {
+		return nil
+	}
+	return s.append(opDelete, k, nil, time.Time{})
+}
+
+// append writes a record and updates the index. must be called inside the lock.
+func (s *FileStore) append(op byte, k string, v []byte, expires time.Time) error {
+	if s.f == nil {
+		return STORE_CLOSED
+	}
+	if s.broken {
+		return STORE_BROKEN
+	}
+	rec := encodeRecord(op, k, v, expires)
+	_, err := s.f.Write(rec)
+	if err != nil {
+		s.dropTail()
+		return err
+	}
+	if old, ok := s.index[k]; ok {
+		s.dead += int64(headerSize + len(k) + old.size + 4)
+	}
+	if op == opSet {
+		s.index[k] = fileEntry{off: s.size + headerSize + int64(len(k)), size: len(v), expires: expires}
+	} else {
+		delete(s.index, k)
+		s.dead += int64(len(rec))
+	}
+	s.size += int64(len(rec))
+
+	if s.dead > 1<<20 && s.dead > s.size/2 {
+		return s.compact()
+	}
+	return nil
+}
+
+// dropTail cuts what a failed write left after the last whole record, so the
+// next record follows it. If that fails the store is marked broken, appending
+// after a torn record would corrupt the middle of the log.
+func (s *FileStore) dropTail() {
+	err := s.f.Truncate(s.size)
+	if err == nil {
+		_, err = s.f.Seek(s.size, io.SeekStart)
+	}
+	if err != nil {
+		log.Printf("cache: can't drop a torn record at the end of %s, no more writes are accepted: %s", s.path, err)
+		s.broken = true
+	}
+}
+
+// Compact rewrites the log with only live, unexpired records.
+func (s *FileStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return STORE_CLOSED
+	}
+	return s.compact()
+}
+
+func (s *FileStore) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".compact*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	// CreateTemp makes the file 0600, keep the log's own mode
+	info, err := s.f.Stat()
+	if err == nil {
+		err = tmp.Chmod(info.Mode().Perm())
+	}
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	now := time.Now()
+	w := bufio.NewWriter(tmp)
+	index := make(map[string]fileEntry, len(s.index))
+	var off int64
+	for k, e := range s.index {
+		if e.expires.Before(now) {
+			continue
+		}
+		v := make([]byte, e.size)
+		_, err = s.f.ReadAt(v, e.off)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		rec := encodeRecord(opSet, k, v, e.expires)
+		_, err = w.Write(rec)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		index[k] = fileEntry{off: off + headerSize + int64(len(k)), size: e.size, expires: e.expires}
+		off += int64(len(rec))
+	}
+	err = w.Flush()
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = os.Rename(tmp.Name(), s.path)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	s.f.Close()
+	s.f = tmp
+	s.index = index
+	s.size = off
+	s.dead = 0
+	return nil
+}
+
+func (s *FileStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.index)
+}
+
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
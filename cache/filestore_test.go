@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.log")
+	s, err := OpenFileStore(path)
+	assert.Nil(t, err, "open failed")
+
+	assert.Nil(t, s.Set("a", []byte("one"), time.Now().Add(time.Minute)), "set failed")
+	assert.Nil(t, s.Set("b", []byte("two"), time.Now().Add(time.Minute)), "set failed")
+	assert.Nil(t, s.Set("a", []byte("uno"), time.Now().Add(time.Minute)), "overwrite failed")
+	assert.Nil(t, s.Set("c", []byte("three"), time.Now().Add(-time.Second)), "set failed")
+	assert.Nil(t, s.Delete("b"), "delete failed")
+
+	b, _, err := s.Get("a")
+	assert.Nil(t, err, "get failed")
+	assert.Equal(t, []byte("uno"), b, "get didn't return the newest value")
+	_, _, err = s.Get("b")
+	assert.Equal(t, NOT_FOUND, err, "deleted key was found")
+	_, _, err = s.Get("c")
+	assert.Equal(t, NOT_FOUND, err, "expired key was found")
+	assert.Nil(t, s.Close(), "close failed")
+
+	// a torn write at the end of the log is dropped on open
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.Write(encodeRecord(opSet, "d", []byte("four"), time.Now().Add(time.Minute))[:10])
+	f.Close()
+
+	s, err = OpenFileStore(path)
+	assert.Nil(t, err, "reopen failed")
+	defer s.Close()
+	b, _, err = s.Get("a")
+	assert.Nil(t, err, "value lost on reopen")
+	assert.Equal(t, []byte("uno"), b, "reopen didn't replay the newest value")
+	_, _, err = s.Get("b")
+	assert.Equal(t, NOT_FOUND, err, "delete lost on reopen")
+	_, _, err = s.Get("d")
+	assert.Equal(t, NOT_FOUND, err, "torn write was replayed")
+
+	before, _ := os.Stat(path)
+	assert.Nil(t, s.Compact(), "compact failed")
+	assert.Equal(t, 1, s.Len(), "compact kept dead or expired records")
+	after, _ := os.Stat(path)
+	assert.Equal(t, before.Mode(), after.Mode(), "compact changed the log's mode")
+	b, _, err = s.Get("a")
+	assert.Nil(t, err, "value lost on compact")
+	assert.Equal(t, []byte("uno"), b, "compact changed a value")
+	assert.Nil(t, s.Set("e", []byte("five"), time.Now().Add(time.Minute)), "set after compact failed")
+	b, _, _ = s.Get("e")
+	assert.Equal(t, []byte("five"), b, "set after compact wrote to the wrong place")
+}
+
+func TestFileStoreFailedWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.log")
+	s, err := OpenFileStore(path)
+	assert.Nil(t, err, "open failed")
+	assert.Nil(t, s.Set("a", []byte("one"), time.Now().Add(time.Minute)), "set failed")
+
+	// what a partial write leaves is cut off before the next record
+	s.f.Write(encodeRecord(opSet, "x", []byte("torn"), time.Now().Add(time.Minute))[:10])
+	s.dropTail()
+	assert.Nil(t, s.Set("b", []byte("two"), time.Now().Add(time.Minute)), "set after a failed write failed")
+	s.Close()
+	s, err = OpenFileStore(path)
+	assert.Nil(t, err, "reopen after a failed write failed")
+	for k, want := range map[string]string{"a": "one", "b": "two"} {
+		b, _, err := s.Get(k)
+		assert.Nil(t, err, "%s lost after a failed write", k)
+		assert.Equal(t, []byte(want), b, "wrong value for %s", k)
+	}
+
+	// a torn record that can't be cut off stops the writes
+	ro, _ := os.Open(path)
+	s.f.Close()
+	s.f = ro
+	assert.NotNil(t, s.Set("c", []byte("three"), time.Now().Add(time.Minute)), "write to a read only file succeeded")
+	assert.Equal(t, STORE_BROKEN, s.Set("d", []byte("four"), time.Now().Add(time.Minute)), "a broken store accepted a write")
+	s.Close()
+}
+
+func TestFileStoreCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.log")
+	s, err := OpenFileStore(path)
+	assert.Nil(t, err, "open failed")
+	s.Set("a", []byte("one"), time.Now().Add(time.Minute))
+	s.Set("b", []byte("two"), time.Now().Add(time.Minute))
+	s.Close()
+
+	// a length in the header bigger than the file is treated as a torn write,
+	// without allocating for it
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	rec := encodeRecord(opSet, "c", []byte("three"), time.Now().Add(time.Minute))
+	rec[13], rec[14], rec[15], rec[16] = 0xff, 0xff, 0xff, 0xff
+	f.Write(rec)
+	f.Close()
+	s, err = OpenFileStore(path)
+	assert.Nil(t, err, "reopen with a bad length at the end failed")
+	assert.Equal(t, 2, s.Len(), "records before the torn write were lost")
+	s.Close()
+
+	// flip a byte in the first record's value
+	b, _ := os.ReadFile(path)
+	b[headerSize+1] ^= 0xff
+	os.WriteFile(path, b, 0644)
+	_, err = OpenFileStore(path)
+	var cerr *CorruptLogError
+	assert.True(t, errors.As(err, &cerr), "corrupt record in the middle of the log wasn't reported")
+	if cerr != nil {
+		assert.Equal(t, int64(0), cerr.Offset, "wrong offset for the corrupt record")
+	}
+	after, _ := os.ReadFile(path)
+	assert.Equal(t, b, after, "open changed a corrupt log")
+}
+
+func TestTwoTier(t *testing.T) {
+	store, err := OpenFileStore(filepath.Join(t.TempDir(), "store.log"))
+	assert.Nil(t, err, "open failed")
+	l1 := NewObjCache(1)
+	tt := NewTwoTier(l1, store)
+	defer tt.Close()
+
+	assert.Nil(t, tt.Set("a", "one", time.Minute), "set failed")
+	assert.Nil(t, tt.Set("b", "two", time.Minute), "set to a full l1 failed")
+	assert.True(t, l1.Check("a"), "set didn't write to l1")
+	_, _, err = store.Get("b")
+	assert.Nil(t, err, "set didn't write to l2")
+
+	l1.Uncache("a")
+	v, err := tt.Get("b")
+	assert.Nil(t, err, "get of an l2 only value failed")
+	assert.Equal(t, "two", v, "l2 returned the wrong value")
+	assert.True(t, l1.Check("b"), "l2 hit wasn't promoted to l1")
+
+	assert.Nil(t, tt.Uncache("b"), "uncache failed")
+	_, err = tt.Get("b")
+	assert.Equal(t, NOT_FOUND, err, "uncache didn't remove from both tiers")
+
+	assert.Nil(t, tt.Set("c", "three", time.Millisecond*10), "set failed")
+	time.Sleep(time.Millisecond * 20)
+	l1.Uncache("c")
+	_, err = tt.Get("c")
+	assert.Equal(t, NOT_FOUND, err, "l2 returned an expired value")
+}
@@ -0,0 +1,52 @@
+package cache
+
+// tag and untag keep the tag index in sync, must be called inside the lock.
+func (h *core) tag(k string, tags []string) {
+	for _, t := range tags {
+		keys, ok := h.tags[t]
+		if !ok {
+			keys = make(map[string]struct{})
+			h.tags[t] = keys
+		}
+		keys[k] = struct{}{}
+	}
+}
+
+func (h *core) untag(k string, tags []string) {
+	for _, t := range tags {
+		keys := h.tags[t]
+		delete(keys, k)
+		if len(keys) == 0 {
+			delete(h.tags, t)
+		}
+	}
+}
+
+// InvalidateTag uncaches every key inserted with tag and returns how many there were.
+func (h *core) InvalidateTag(tag string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	keys := make([]string, 0, len(h.tags[tag]))
+	for k := range h.tags[tag] {
+		keys = append(keys, k)
+	}
+	for _, k := range keys {
+		h.remove(k)
+	}
+	return len(keys)
+}
+
+// InvalidatePrefix uncaches every key that starts with prefix and returns how many
+// there were. The keys are found in the cache's prefix tree, not by scanning the map.
+func (h *core) InvalidatePrefix(prefix string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var keys []string
+	h.prefixes.walkPrefix(prefix, func(k string) {
+		keys = append(keys, k)
+	})
+	for _, k := range keys {
+		h.remove(k)
+	}
+	return len(keys)
+}
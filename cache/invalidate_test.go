@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidateTag(t *testing.T) {
+	c := NewObjCache(10)
+	defer c.Close()
+
+	c.Insert("t1:u1", 1, time.Second, "tenant1", "users")
+	c.Insert("t1:u2", 2, time.Second, "tenant1", "users")
+	c.Insert("t2:u1", 3, time.Second, "tenant2", "users")
+	c.Insert("t1:cfg", 4, time.Second, "tenant1")
+
+	assert.Equal(t, 3, c.InvalidateTag("tenant1"), "invalidate tag removed the wrong number of keys")
+	assert.False(t, c.Check("t1:u1"), "tagged key wasn't invalidated")
+	assert.False(t, c.Check("t1:cfg"), "tagged key wasn't invalidated")
+	assert.True(t, c.Check("t2:u1"), "key with a different tag was invalidated")
+	assert.Equal(t, 1, c.records, "record count is wrong after invalidate")
+
+	assert.Equal(t, 1, c.InvalidateTag("users"), "invalidated keys were still indexed under their other tags")
+	assert.Equal(t, 0, c.InvalidateTag("tenant1"), "invalidate of an empty tag removed keys")
+	assert.Empty(t, c.tags, "tag index wasn't cleaned up")
+
+	// overwriting an entry replaces its tags
+	c.Insert("a", 1, time.Second, "old")
+	c.Set("a", 2, time.Second, "new")
+	assert.Equal(t, 0, c.InvalidateTag("old"), "set didn't drop the old tags")
+	assert.Equal(t, 1, c.InvalidateTag("new"), "set didn't add the new tags")
+}
+
+func TestInvalidatePrefix(t *testing.T) {
+	c := NewObjCache(10)
+	defer c.Close()
+
+	c.Insert("tenant1:user1", 1, time.Second)
+	c.Insert("tenant1:user2", 2, time.Second)
+	c.Insert("tenant10:user1", 3, time.Second)
+	c.Insert("tenant2:user1", 4, time.Second)
+
+	assert.Equal(t, 2, c.InvalidatePrefix("tenant1:"), "invalidate prefix removed the wrong number of keys")
+	assert.False(t, c.Check("tenant1:user1"), "key under prefix wasn't invalidated")
+	assert.True(t, c.Check("tenant10:user1"), "key outside prefix was invalidated")
+
+	// uncached keys must leave the index too
+	c.Uncache("tenant2:user1")
+	assert.Equal(t, 1, c.InvalidatePrefix("tenant"), "uncached key was still in the prefix index")
+	assert.Equal(t, 0, c.records, "record count is wrong after invalidate")
+}
@@ -0,0 +1,81 @@
+package cache
+
+import "time"
+
+// Len returns the number of entries in the cache, including expired entries
+// the sweeper hasn't reached yet. Keys and Range skip those.
+func (h *core) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.c)
+}
+
+// Keys returns every key in the cache, in no particular order.
+func (h *core) Keys() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	now := time.Now()
+	keys := make([]string, 0, len(h.c))
+	for k, v := range h.c {
+		if v.t.After(now) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Range calls fn for each entry until fn returns false. It iterates over a copy
+// taken under the read lock, so fn can call back into the cache and writers
+// aren't blocked while it runs. TimeoutCache values are nil.
+func (h *core) Range(fn func(k string, v interface{}, expiresAt time.Time) bool) {
+	type entry struct {
+		k string
+		v interface{}
+		t time.Time
+	}
+	now := time.Now()
+	h.mu.RLock()
+	entries := make([]entry, 0, len(h.c))
+	for k, v := range h.c {
+		if v.t.After(now) {
+			entries = append(entries, entry{k, v.v, v.t})
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, e := range entries {
+		if !fn(e.k, e.v, e.t) {
+			return
+		}
+	}
+}
+
+// Peek returns the value at k without extending a sliding expiration or
+// counting as a hit or miss.
+func (h *core) Peek(k string) (interface{}, error) {
+	if h.closed() {
+		return nil, CACHE_CLOSED
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	v, ok := h.c[k]
+	if !ok || v.t.Before(time.Now()) {
+		return nil, NOT_FOUND
+	}
+	if v.absent {
+		return nil, ABSENT
+	}
+	return v.v, nil
+}
+
+// Flush uncaches every entry
+func (h *core) Flush() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.c = make(map[string]*val)
+	h.expiry = nil
+	h.records = 0
+	h.cost = 0
+	h.tags = make(map[string]map[string]struct{})
+	h.prefixes = &radix{}
+}
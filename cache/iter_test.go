@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLenKeysRange(t *testing.T) {
+	c := NewObjCache(10)
+	defer c.Close()
+
+	c.Insert("a", 1, time.Second)
+	c.Insert("b", 2, time.Second)
+	c.Insert("c", 3, time.Second)
+
+	assert.Equal(t, 3, c.Len(), "wrong length")
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, c.Keys(), "wrong keys")
+
+	seen := map[string]interface{}{}
+	c.Range(func(k string, v interface{}, expiresAt time.Time) bool {
+		seen[k] = v
+		assert.True(t, expiresAt.After(time.Now()), "range returned the wrong expiration")
+		// calling back into the cache from fn must not deadlock
+		c.Check(k)
+		return true
+	})
+	assert.Equal(t, map[string]interface{}{"a": 1, "b": 2, "c": 3}, seen, "range didn't visit every entry")
+
+	n := 0
+	c.Range(func(k string, v interface{}, expiresAt time.Time) bool {
+		n++
+		return false
+	})
+	assert.Equal(t, 1, n, "range didn't stop when fn returned false")
+}
+
+func TestPeek(t *testing.T) {
+	c := NewObjCache(10, Sliding(0))
+	defer c.Close()
+
+	c.Insert("a", 1, time.Millisecond*50)
+	c.mu.RLock()
+	exp := c.c["a"].t
+	c.mu.RUnlock()
+
+	time.Sleep(time.Millisecond * 5)
+	v, err := c.Peek("a")
+	assert.Nil(t, err, "peek failed")
+	assert.Equal(t, 1, v, "peek returned the wrong value")
+	_, err = c.Peek("z")
+	assert.Equal(t, NOT_FOUND, err, "peek of a missing key didn't return NOT_FOUND")
+
+	c.mu.RLock()
+	assert.Equal(t, exp, c.c["a"].t, "peek extended a sliding expiration")
+	c.mu.RUnlock()
+	assert.Equal(t, int64(0), c.Stats().Hits+c.Stats().Misses, "peek counted as a hit or miss")
+}
+
+func TestIterConcurrentWrites(t *testing.T) {
+	c := NewTimeoutCache(1000)
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 500; i++ {
+			c.Insert(fmt.Sprint(i), time.Second)
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		c.Len()
+		c.Keys()
+		c.Range(func(k string, v interface{}, expiresAt time.Time) bool { return true })
+	}
+	wg.Wait()
+	assert.Equal(t, 500, c.Len(), "wrong length after concurrent writes")
+}
+
+func TestFlush(t *testing.T) {
+	c := NewObjCache(10)
+	defer c.Close()
+
+	c.Insert("a", 1, time.Second, "tag")
+	c.Insert("b", 2, time.Second)
+	c.Flush()
+
+	assert.Equal(t, 0, c.Len(), "flush left entries")
+	assert.Equal(t, 0, c.records, "flush didn't reset the record count")
+	assert.Equal(t, 0, c.InvalidateTag("tag"), "flush didn't reset the tag index")
+	assert.Equal(t, 0, c.InvalidatePrefix(""), "flush didn't reset the prefix index")
+	assert.Nil(t, c.Insert("a", 1, time.Second), "insert after flush failed")
+}
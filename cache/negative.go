@@ -0,0 +1,40 @@
+package cache
+
+import "time"
+
+// Jitter adds a random extra of up to fraction * ttl to every entry's ttl, so
+// entries inserted together don't all expire together. Jitter(0.1) turns a
+// 1 minute ttl into somewhere between 1 minute and 66 seconds.
+func Jitter(fraction float64) CacheOption {
+	return func(cache WithOptions) {
+		if c, ok := cache.(ttlOptions); ok {
+			c.setJitter(fraction)
+		}
+	}
+}
+
+// NegativeTTL sets how long ObjCache.SetAbsent entries live. The default is 10 seconds.
+// Other caches ignore it.
+func NegativeTTL(d time.Duration) CacheOption {
+	return func(cache WithOptions) {
+		if c, ok := cache.(ttlOptions); ok {
+			c.setNegativeTTL(d)
+		}
+	}
+}
+
+type ttlOptions interface {
+	setJitter(fraction float64)
+	setNegativeTTL(d time.Duration)
+}
+
+func (h *core) setJitter(fraction float64) {
+	h.jitter = fraction
+}
+
+// core has no negative entries, only ObjCache uses the setting
+func (h *core) setNegativeTTL(d time.Duration) {}
+
+func (h *ObjCache) setNegativeTTL(d time.Duration) {
+	h.negativeTTL = d
+}
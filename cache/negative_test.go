@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJitter(t *testing.T) {
+	c := NewTimeoutCache(100, Jitter(0.5))
+	defer c.Close()
+
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		c.Insert(fmt.Sprint(i), time.Minute)
+	}
+
+	distinct := map[time.Time]bool{}
+	c.Range(func(k string, v interface{}, expiresAt time.Time) bool {
+		ttl := expiresAt.Sub(start)
+		assert.True(t, ttl >= time.Minute && ttl <= time.Second*91, "jittered ttl out of range")
+		distinct[expiresAt.Truncate(time.Second)] = true
+		return true
+	})
+	assert.True(t, len(distinct) > 10, "jitter didn't spread expirations")
+}
+
+func TestNegativeCaching(t *testing.T) {
+	c := NewObjCache(10, NegativeTTL(time.Millisecond*20), ScanRate(time.Millisecond*5))
+	defer c.Close()
+
+	assert.Nil(t, c.SetAbsent("a"), "set absent failed")
+	v, err := c.Get("a")
+	assert.Equal(t, ABSENT, err, "negative entry didn't return ABSENT")
+	assert.Nil(t, v, "negative entry returned a value")
+	_, err = c.Get("b")
+	assert.Equal(t, NOT_FOUND, err, "uncached key didn't return NOT_FOUND")
+	assert.False(t, c.Check("a"), "check reported a negative entry as cached")
+	ok, err := c.CompareAndSwap("a", nil, 1, time.Minute)
+	assert.False(t, ok, "compare and swap matched a negative entry to nil")
+	assert.Equal(t, ABSENT, err, "compare and swap of a negative entry didn't return ABSENT")
+	_, err = c.Get("a")
+	assert.Equal(t, ABSENT, err, "compare and swap replaced a negative entry")
+
+	time.Sleep(time.Millisecond * 40)
+	_, err = c.Get("a")
+	assert.Equal(t, NOT_FOUND, err, "negative entry didn't use the negative ttl")
+
+	calls := 0
+	load := func(k string) (interface{}, error) {
+		calls++
+		return nil, NOT_FOUND
+	}
+	_, err = c.GetOrLoad("c", time.Minute, load)
+	assert.Equal(t, ABSENT, err, "loader NOT_FOUND didn't return ABSENT")
+	_, err = c.GetOrLoad("c", time.Minute, load)
+	assert.Equal(t, ABSENT, err, "negative entry from the loader wasn't cached")
+	assert.Equal(t, 1, calls, "loader called for a negatively cached key")
+
+	assert.Nil(t, c.Set("c", 3, time.Minute), "set over a negative entry failed")
+	v, err = c.Get("c")
+	assert.Nil(t, err, "set didn't replace the negative entry")
+	assert.Equal(t, 3, v, "set didn't replace the negative entry")
+}
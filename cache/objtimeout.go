@@ -1,111 +1,92 @@
 package cache
 
 import (
-	"sync"
 	"time"
 )
 
-type WithOptions interface {
-	SetScanRate(duration time.Duration)
-}
-
-type CacheOption func(cache WithOptions)
-
-func ScanRate(duration time.Duration) CacheOption {
-	return func(cache WithOptions) {
-		cache.SetScanRate(duration)
-	}
-}
+type ObjCache struct {
+	core
 
-type val struct {
-	t time.Time
-	v interface{}
-}
+	codec        Codec
+	snapfile     string
+	snapinterval time.Duration
+	snapdone     chan struct{}
 
-type ObjCache struct {
-	c          map[string]*val
-	records    int
-	maxrecords int
-	mu         sync.RWMutex
-	scanRate   time.Duration
+	negativeTTL time.Duration
 }
 
 func NewObjCache(maxrecords int, opts ...CacheOption) *ObjCache {
-	if maxrecords < 1 {
-		maxrecords = 1
-	}
-
 	t := &ObjCache{
-		c:          make(map[string]*val),
-		records:    0,
-		maxrecords: maxrecords,
-		scanRate:   time.Second * 30,
+		codec:       GobCodec{},
+		negativeTTL: time.Second * 10,
 	}
+	t.init(maxrecords)
 
 	for _, opt := range opts {
 		opt(t)
 	}
 
-	go func() {
-		ticker := time.NewTicker(t.scanRate)
-		for range ticker.C {
-			t.mu.Lock()
-			for k, v := range t.c {
-				if v.t.Before(time.Now()) {
-					delete(t.c, k)
-					t.records--
-				}
-			}
-			t.mu.Unlock()
+	t.start()
+
+	if t.snapfile != "" {
+		t.loadSnapshotFile()
+		if t.snapinterval > 0 {
+			t.snapdone = make(chan struct{})
+			t.snapshotLoop()
 		}
-	}()
+	}
 	return t
 }
 
-func (h *ObjCache) SetScanRate(duration time.Duration) {
-	h.scanRate = duration
+// Close stops the sweeper goroutine, and writes a final snapshot if SnapshotFile is set.
+// After Close, writes and Get return CACHE_CLOSED and Check returns false.
+func (h *ObjCache) Close() {
+	h.core.Close()
+	if h.snapdone != nil {
+		<-h.snapdone
+	}
 }
 
-func (h *ObjCache) Check(k string) bool {
-	h.mu.Lock()
-	_, ok := h.c[k]
-	h.mu.Unlock()
-	return ok
+// Get returns the value stored at k. It returns NOT_FOUND if k isn't cached,
+// and ABSENT if k is cached as missing by SetAbsent.
+func (h *ObjCache) Get(k string) (interface{}, error) {
+	return h.get(k)
 }
 
-func (h *ObjCache) Get(k string) (interface{}, bool) {
-	h.mu.Lock()
-	v, ok := h.c[k]
-	h.mu.Unlock()
-	if v != nil {
-		return v.v, ok
-	}
-	return nil, ok
+// Insert caches v at s for duration. It returns ALREADY_EXISTS if s is cached,
+// use Set to overwrite. tags can later be passed to InvalidateTag.
+func (h *ObjCache) Insert(s string, v interface{}, duration time.Duration, tags ...string) error {
+	return h.insert(s, h.newVal(v, duration, tags))
 }
 
-func (h *ObjCache) Insert(s string, v interface{}, duration time.Duration) error {
-	expiration := time.Now().Add(duration)
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	if len(h.c) >= h.maxrecords {
-		return CACHEFULL
-	}
-	h.c[s] = &val{t: expiration, v: v}
-	return nil
+// Set caches v at s for duration, overwriting any value already there.
+func (h *ObjCache) Set(s string, v interface{}, duration time.Duration, tags ...string) error {
+	return h.set(s, h.newVal(v, duration, tags))
 }
 
-func (h ObjCache) Uncache(s string) {
-	h.mu.Lock()
-	delete(h.c, s)
-	h.mu.Unlock()
-	h.records--
+// InsertIfAbsent caches v at s unless s is already cached. It returns the value
+// now in the cache, and true if that value was already there.
+func (h *ObjCache) InsertIfAbsent(s string, v interface{}, duration time.Duration, tags ...string) (interface{}, bool, error) {
+	return h.insertIfAbsent(s, h.newVal(v, duration, tags))
 }
 
-func (h ObjCache) UncacheMany(s []string) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	for _, k := range s {
-		delete(h.c, k)
-		h.records--
-	}
+// Replace caches v at s only if s is already cached, otherwise it returns NOT_FOUND.
+func (h *ObjCache) Replace(s string, v interface{}, duration time.Duration, tags ...string) error {
+	return h.replace(s, h.newVal(v, duration, tags))
+}
+
+// CompareAndSwap caches v at s only if the value at s == old. The entry keeps
+// its tags. Like sync.Map, it panics if old isn't comparable. A key cached
+// as absent returns ABSENT, like Get, and isn't swapped.
+func (h *ObjCache) CompareAndSwap(s string, old, v interface{}, duration time.Duration) (bool, error) {
+	return h.compareAndSwap(s, old, h.newVal(v, duration, nil))
+}
+
+// SetAbsent caches k as known to be missing, so Get returns ABSENT instead of
+// NOT_FOUND until the entry expires. Negative entries use the NegativeTTL, which
+// is usually shorter than a normal ttl.
+func (h *ObjCache) SetAbsent(k string, tags ...string) error {
+	e := h.newVal(nil, h.negativeTTL, tags)
+	e.absent = true
+	return h.set(k, e)
 }
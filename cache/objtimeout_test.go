@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -41,16 +42,16 @@ func TestGet(t *testing.T) {
 	c.Insert("b", 2, time.Second)
 	c.Insert("c", 3, time.Second)
 
-	v, ok := c.Get("a")
-	assert.True(t, ok, "get returned false for existing value")
+	v, err := c.Get("a")
+	assert.Nil(t, err, "get returned an error for existing value")
 	assert.Equal(t, 1, v.(int), "get return unexpected value %s", v)
 
-	v, ok = c.Get("b")
-	assert.True(t, ok, "get returned false for existing value")
+	v, err = c.Get("b")
+	assert.Nil(t, err, "get returned an error for existing value")
 	assert.Equal(t, 2, v.(int), "get return unexpected value %s", v)
 
-	v, ok = c.Get("c")
-	assert.False(t, ok, "get returned true for non-existent value")
+	v, err = c.Get("c")
+	assert.Equal(t, NOT_FOUND, err, "get didn't return NOT_FOUND for missing value")
 	assert.Nil(t, v,  "get return unexpected value %s", v)
 }
 
@@ -60,14 +61,14 @@ func TestUncache(t *testing.T) {
 	c.Insert("a", 1, time.Second)
 	c.Insert("b", 2, time.Second)
 
-	v, ok := c.Get("a")
-	assert.True(t, ok, "get returned false for existing value")
+	v, err := c.Get("a")
+	assert.Nil(t, err, "get returned an error for existing value")
 	assert.Equal(t, 1, v.(int), "get return unexpected value %s", v)
 
 	c.Uncache("a")
 
-	v, ok = c.Get("a")
-	assert.False(t, ok, "get returned false for existing value")
+	v, err = c.Get("a")
+	assert.Equal(t, NOT_FOUND, err, "get didn't return NOT_FOUND for missing value")
 	assert.Nil(t, v, "get return unexpected value %s", v)
 
 }
@@ -81,38 +82,38 @@ func TestUncacheMany(t *testing.T) {
 	c.Insert("d", 4, time.Second)
 	c.Insert("e", 5, time.Second)
 
-	v, ok := c.Get("a")
-	assert.True(t, ok, "get returned false for existing value")
+	v, err := c.Get("a")
+	assert.Nil(t, err, "get returned an error for existing value")
 	assert.Equal(t, 1, v.(int), "get return unexpected value %s", v)
-	v, ok = c.Get("b")
-	assert.True(t, ok, "get returned false for existing value")
+	v, err = c.Get("b")
+	assert.Nil(t, err, "get returned an error for existing value")
 	assert.Equal(t, 2, v.(int), "get return unexpected value %s", v)
-	v, ok = c.Get("c")
-	assert.True(t, ok, "get returned false for existing value")
+	v, err = c.Get("c")
+	assert.Nil(t, err, "get returned an error for existing value")
 	assert.Equal(t, 3, v.(int), "get return unexpected value %s", v)
-	v, ok = c.Get("d")
-	assert.True(t, ok, "get returned false for existing value")
+	v, err = c.Get("d")
+	assert.Nil(t, err, "get returned an error for existing value")
 	assert.Equal(t, 4, v.(int), "get return unexpected value %s", v)
-	v, ok = c.Get("e")
-	assert.True(t, ok, "get returned false for existing value")
+	v, err = c.Get("e")
+	assert.Nil(t, err, "get returned an error for existing value")
 	assert.Equal(t, 5, v.(int), "get return unexpected value %s", v)
 
 	c.UncacheMany([]string{"a","b","c"})
 
-	v, ok = c.Get("a")
-	assert.False(t, ok, "get returned false for existing value")
+	v, err = c.Get("a")
+	assert.Equal(t, NOT_FOUND, err, "get didn't return NOT_FOUND for missing value")
 	assert.Nil(t, v, "get return unexpected value %s", v)
-	v, ok = c.Get("b")
-	assert.False(t, ok, "get returned false for existing value")
+	v, err = c.Get("b")
+	assert.Equal(t, NOT_FOUND, err, "get didn't return NOT_FOUND for missing value")
 	assert.Nil(t, v, "get return unexpected value %s", v)
-	v, ok = c.Get("c")
-	assert.False(t, ok, "get returned false for existing value")
+	v, err = c.Get("c")
+	assert.Equal(t, NOT_FOUND, err, "get didn't return NOT_FOUND for missing value")
 	assert.Nil(t, v, "get return unexpected value %s", v)
-	v, ok = c.Get("d")
-	assert.True(t, ok, "get returned false for existing value")
+	v, err = c.Get("d")
+	assert.Nil(t, err, "get returned an error for existing value")
 	assert.Equal(t, 4, v.(int), "get return unexpected value %s", v)
-	v, ok = c.Get("e")
-	assert.True(t, ok, "get returned false for existing value")
+	v, err = c.Get("e")
+	assert.Nil(t, err, "get returned an error for existing value")
 	assert.Equal(t, 5, v.(int), "get return unexpected value %s", v)
 
 }
@@ -126,14 +127,14 @@ func TestDuplicateInserts(t *testing.T) {
 	c.Insert("d", 4, time.Second)
 	c.Insert("e", 5, time.Second)
 
-	v, ok := c.Get("a")
-	assert.True(t, ok, "get returned false for existing value")
+	v, err := c.Get("a")
+	assert.Nil(t, err, "get returned an error for existing value")
 	assert.Equal(t, 1, v.(int), "get return unexpected value %s", v)
 
 	c.Uncache("a")
 
-	v, ok = c.Get("a")
-	assert.False(t, ok, "get returned false for existing value")
+	v, err = c.Get("a")
+	assert.Equal(t, NOT_FOUND, err, "get didn't return NOT_FOUND for missing value")
 	assert.Nil(t, v, "get return unexpected value %s", v)
 }
 
@@ -143,13 +144,128 @@ func TestScanRate(t *testing.T) {
 
 	c.Insert("a", 1, time.Millisecond * 100)
 
-	v, ok := c.Get("a")
-	assert.True(t, ok, "get returned false for existing value")
+	v, err := c.Get("a")
+	assert.Nil(t, err, "get returned an error for existing value")
 	assert.Equal(t, 1, v.(int), "get return unexpected value %s", v)
 
 	time.Sleep(time.Millisecond * 120)
 
-	v, ok = c.Get("a")
-	assert.False(t, ok, "get returned false for existing value")
+	v, err = c.Get("a")
+	assert.Equal(t, NOT_FOUND, err, "get didn't return NOT_FOUND for missing value")
 	assert.Nil(t, v, "get return unexpected value %s", v)
-}
\ No newline at end of file
+}
+
+func TestSliding(t *testing.T) {
+	c := NewObjCache(2, ScanRate(time.Millisecond*10), Sliding(0))
+
+	c.Insert("a", 1, time.Millisecond*60)
+	c.Insert("b", 2, time.Millisecond*60)
+
+	// keep reading a, b should expire
+	for i := 0; i < 5; i++ {
+		time.Sleep(time.Millisecond * 20)
+		_, err := c.Get("a")
+		assert.Nil(t, err, "get returned an error for a value that's being read")
+	}
+
+	v, err := c.Get("a")
+	assert.Nil(t, err, "get returned an error for a value that's being read")
+	assert.Equal(t, 1, v.(int), "get return unexpected value")
+	assert.False(t, c.Check("b"), "check returned true for a value that wasn't read")
+}
+
+func TestSlidingMaxLifetime(t *testing.T) {
+	c := NewObjCache(2, ScanRate(time.Millisecond*10), Sliding(time.Millisecond*100))
+
+	c.Insert("a", 1, time.Millisecond*60)
+
+	for i := 0; i < 4; i++ {
+		time.Sleep(time.Millisecond * 20)
+		assert.True(t, c.Check("a"), "check returned false for a value that's being read")
+	}
+	time.Sleep(time.Millisecond * 40)
+	assert.False(t, c.Check("a"), "check returned true for a value past its max lifetime")
+}
+
+func TestClose(t *testing.T) {
+	c := NewObjCache(2)
+
+	assert.Nil(t, c.Insert("a", 1, time.Second), "insert failed on open cache")
+	c.Close()
+
+	assert.Equal(t, CACHE_CLOSED, c.Insert("b", 2, time.Second), "insert after close didn't return CACHE_CLOSED")
+	v, err := c.Get("a")
+	assert.Equal(t, CACHE_CLOSED, err, "get after close didn't return CACHE_CLOSED")
+	assert.Nil(t, v, "get after close returned a value")
+	assert.False(t, c.Check("a"), "check after close returned true")
+}
+
+func TestWithContext(t *testing.T) {
+	ctx, canc := context.WithCancel(context.Background())
+	c := NewObjCache(2, WithContext(ctx))
+
+	assert.Nil(t, c.Insert("a", 1, time.Second), "insert failed on open cache")
+	canc()
+	c.sweeper.wait()
+	assert.Equal(t, CACHE_CLOSED, c.Insert("b", 2, time.Second), "insert after cancel didn't return CACHE_CLOSED")
+}
+
+func TestSetScanRate(t *testing.T) {
+	c := NewObjCache(2)
+	defer c.Close()
+
+	c.Insert("a", 1, time.Millisecond*20)
+	c.SetScanRate(time.Millisecond * 10)
+	time.Sleep(time.Millisecond * 50)
+
+	c.mu.RLock()
+	n := len(c.c)
+	c.mu.RUnlock()
+	assert.Equal(t, 0, n, "changing the scan rate on a running cache had no effect")
+}
+
+func TestInsertSemantics(t *testing.T) {
+	c := NewObjCache(2)
+	defer c.Close()
+
+	assert.Nil(t, c.Insert("a", 1, time.Second), "insert failed")
+	assert.Equal(t, ALREADY_EXISTS, c.Insert("a", 2, time.Second), "insert overwrote an existing key")
+	v, _ := c.Get("a")
+	assert.Equal(t, 1, v, "failed insert changed the value")
+
+	assert.Nil(t, c.Set("a", 2, time.Second), "set failed on an existing key")
+	v, _ = c.Get("a")
+	assert.Equal(t, 2, v, "set didn't overwrite the value")
+	assert.Equal(t, 1, c.records, "overwrite changed the record count")
+
+	v, loaded, err := c.InsertIfAbsent("a", 3, time.Second)
+	assert.Nil(t, err, "insert if absent failed")
+	assert.True(t, loaded, "insert if absent didn't report the existing value")
+	assert.Equal(t, 2, v, "insert if absent didn't return the existing value")
+	v, loaded, err = c.InsertIfAbsent("b", 3, time.Second)
+	assert.Nil(t, err, "insert if absent failed")
+	assert.False(t, loaded, "insert if absent reported a missing value as existing")
+	assert.Equal(t, 3, v, "insert if absent didn't return the inserted value")
+	assert.Equal(t, 2, c.records, "record count is wrong")
+
+	assert.Equal(t, NOT_FOUND, c.Replace("c", 4, time.Second), "replace inserted a missing key")
+	assert.Nil(t, c.Replace("b", 4, time.Second), "replace failed on an existing key")
+	v, _ = c.Get("b")
+	assert.Equal(t, 4, v, "replace didn't overwrite the value")
+
+	swapped, err := c.CompareAndSwap("b", 3, 5, time.Second)
+	assert.Nil(t, err, "compare and swap failed")
+	assert.False(t, swapped, "compare and swap swapped a value that didn't match")
+	swapped, err = c.CompareAndSwap("b", 4, 5, time.Second)
+	assert.Nil(t, err, "compare and swap failed")
+	assert.True(t, swapped, "compare and swap didn't swap a matching value")
+	v, _ = c.Get("b")
+	assert.Equal(t, 5, v, "compare and swap didn't store the new value")
+	_, err = c.CompareAndSwap("c", nil, 5, time.Second)
+	assert.Equal(t, NOT_FOUND, err, "compare and swap on a missing key didn't return NOT_FOUND")
+
+	c.Uncache("a")
+	c.Uncache("a")
+	c.UncacheMany([]string{"a", "z"})
+	assert.Equal(t, 1, c.records, "uncache of missing keys changed the record count")
+}
@@ -0,0 +1,116 @@
+package cache
+
+import "strings"
+
+// radix is a compressed prefix tree of cache keys. It backs InvalidatePrefix
+// so finding the keys under a prefix doesn't need a scan of the whole map.
+type radix struct {
+	root rnode
+}
+
+type rnode struct {
+	prefix   string
+	children map[byte]*rnode
+	leaf     bool
+}
+
+func commonPrefixLen(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+func (r *radix) insert(key string) {
+	n := &r.root
+	for {
+		if key == "" {
+			n.leaf = true
+			return
+		}
+		if n.children == nil {
+			n.children = make(map[byte]*rnode)
+		}
+		c := n.children[key[0]]
+		if c == nil {
+			n.children[key[0]] = &rnode{prefix: key, leaf: true}
+			return
+		}
+		l := commonPrefixLen(key, c.prefix)
+		if l < len(c.prefix) {
+			// split c so the shared part of the edge is its own node
+			split := &rnode{prefix: c.prefix[:l], children: make(map[byte]*rnode)}
+			c.prefix = c.prefix[l:]
+			split.children[c.prefix[0]] = c
+			n.children[key[0]] = split
+			c = split
+		}
+		key = key[l:]
+		n = c
+	}
+}
+
+func (r *radix) delete(key string) {
+	var path []*rnode
+	n := &r.root
+	for key != "" {
+		c := n.children[key[0]]
+		if c == nil || !strings.HasPrefix(key, c.prefix) {
+			return
+		}
+		path = append(path, n)
+		key = key[len(c.prefix):]
+		n = c
+	}
+	if !n.leaf {
+		return
+	}
+	n.leaf = false
+
+	// prune empty nodes and merge nodes left with a single child, bottom up
+	for i := len(path) - 1; i >= 0; i-- {
+		parent := path[i]
+		switch {
+		case !n.leaf && len(n.children) == 0:
+			delete(parent.children, n.prefix[0])
+		case !n.leaf && len(n.children) == 1:
+			for _, only := range n.children {
+				only.prefix = n.prefix + only.prefix
+				parent.children[only.prefix[0]] = only
+			}
+		}
+		n = parent
+	}
+}
+
+// walkPrefix calls fn with every key that starts with prefix
+func (r *radix) walkPrefix(prefix string, fn func(key string)) {
+	n := &r.root
+	base := ""
+	for prefix != "" {
+		c := n.children[prefix[0]]
+		if c == nil {
+			return
+		}
+		if strings.HasPrefix(prefix, c.prefix) {
+			prefix = prefix[len(c.prefix):]
+		} else if strings.HasPrefix(c.prefix, prefix) {
+			prefix = ""
+		} else {
+			return
+		}
+		base += c.prefix
+		n = c
+	}
+	n.walk(base, fn)
+}
+
+func (n *rnode) walk(key string, fn func(key string)) {
+	if n.leaf {
+		fn(key)
+	}
+	for _, c := range n.children {
+		c.walk(key+c.prefix, fn)
+	}
+}
@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func walked(r *radix, prefix string) []string {
+	keys := []string{}
+	r.walkPrefix(prefix, func(k string) { keys = append(keys, k) })
+	return keys
+}
+
+func TestRadix(t *testing.T) {
+	r := &radix{}
+	for _, k := range []string{"tenant1:user1", "tenant1:user2", "tenant10:user1", "tenant2:user1", "t"} {
+		r.insert(k)
+	}
+
+	assert.ElementsMatch(t, []string{"tenant1:user1", "tenant1:user2"}, walked(r, "tenant1:"), "wrong keys under prefix")
+	assert.ElementsMatch(t, []string{"tenant1:user1", "tenant1:user2", "tenant10:user1"}, walked(r, "tenant1"), "wrong keys under prefix")
+	assert.ElementsMatch(t, []string{"tenant1:user1", "tenant1:user2", "tenant10:user1", "tenant2:user1", "t"}, walked(r, ""), "empty prefix didn't walk every key")
+	assert.Empty(t, walked(r, "tenant3"), "walk found keys under a missing prefix")
+
+	r.delete("tenant1:user1")
+	r.delete("tenant1:user1")
+	r.delete("tenant")
+	assert.ElementsMatch(t, []string{"tenant1:user2", "tenant10:user1"}, walked(r, "tenant1"), "delete removed the wrong keys")
+
+	r.delete("tenant1:user2")
+	r.delete("tenant10:user1")
+	r.delete("tenant2:user1")
+	assert.ElementsMatch(t, []string{"t"}, walked(r, ""), "delete left keys behind")
+	r.delete("t")
+	assert.Empty(t, r.root.children, "delete didn't prune empty nodes")
+}
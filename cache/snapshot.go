@@ -0,0 +1,239 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// A Codec turns cached values into bytes and back for Snapshot and Restore.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(b []byte) (interface{}, error)
+}
+
+// GobCodec is the default codec. Concrete value types must be registered
+// with gob.Register before a snapshot is written or restored.
+type GobCodec struct{}
+
+func (GobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(&v)
+	return buf.Bytes(), err
+}
+
+func (GobCodec) Unmarshal(b []byte) (interface{}, error) {
+	var v interface{}
+	err := gob.NewDecoder(bytes.NewReader(b)).Decode(&v)
+	return v, err
+}
+
+// JSONCodec stores values as json. If New is set, values are decoded into the
+// pointer it returns, otherwise they decode the way json.Unmarshal does into an interface{}.
+type JSONCodec struct {
+	New func() interface{}
+}
+
+func (c JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (c JSONCodec) Unmarshal(b []byte) (interface{}, error) {
+	if c.New == nil {
+		var v interface{}
+		err := json.Unmarshal(b, &v)
+		return v, err
+	}
+	v := c.New()
+	err := json.Unmarshal(b, v)
+	return v, err
+}
+
+// WithCodec sets the codec ObjCache uses for snapshots. Other caches ignore it.
+func WithCodec(c Codec) CacheOption {
+	return func(cache WithOptions) {
+		if s, ok := cache.(snapshotOptions); ok {
+			s.setCodec(c)
+		}
+	}
+}
+
+// SnapshotFile makes ObjCache restore itself from path on startup and write a
+// snapshot back to path every interval and on Close. Writes go to a temp file
+// in the same directory that is renamed over path, so a crash never leaves a
+// half written snapshot. Other caches ignore it.
+func SnapshotFile(path string, interval time.Duration) CacheOption {
+	return func(cache WithOptions) {
+		if s, ok := cache.(snapshotOptions); ok {
+			s.setSnapshotFile(path, interval)
+		}
+	}
+}
+
+type snapshotOptions interface {
+	setCodec(c Codec)
+	setSnapshotFile(path string, interval time.Duration)
+}
+
+// snapshotEntry is what's written for each record. Times are stored relative
+// to when the snapshot was taken so the remaining ttl survives a restart.
+type snapshotEntry struct {
+	Key       string
+	Value     []byte
+	Remaining time.Duration
+	TTL       time.Duration
+	Age       time.Duration
+	Cost      int64
+	Tags      []string
+	Absent    bool
+}
+
+func (h *ObjCache) setCodec(c Codec) {
+	h.codec = c
+}
+
+func (h *ObjCache) setSnapshotFile(path string, interval time.Duration) {
+	h.snapfile = path
+	h.snapinterval = interval
+}
+
+// Snapshot writes every unexpired entry to w using the cache's codec.
+func (h *ObjCache) Snapshot(w io.Writer) error {
+	now := time.Now()
+	type pair struct {
+		k string
+		v *val
+	}
+	h.mu.RLock()
+	pairs := make([]pair, 0, len(h.c))
+	for k, v := range h.c {
+		if v.t.After(now) {
+			cp := *v
+			pairs = append(pairs, pair{k, &cp})
+		}
+	}
+	h.mu.RUnlock()
+
+	enc := gob.NewEncoder(w)
+	for _, p := range pairs {
+		b, err := h.codec.Marshal(p.v.v)
+		if err != nil {
+			return err
+		}
+		err = enc.Encode(snapshotEntry{
+			Key:       p.k,
+			Value:     b,
+			Remaining: p.v.t.Sub(now),
+			TTL:       p.v.ttl,
+			Age:       now.Sub(p.v.created),
+			Cost:      p.v.cost,
+			Tags:      p.v.tags,
+			Absent:    p.v.absent,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore loads entries written by Snapshot. Existing keys are overwritten,
+// entries whose ttl ran out are skipped. Restore stops with CACHEFULL if the
+// snapshot holds more records than the cache allows.
+func (h *ObjCache) Restore(r io.Reader) error {
+	dec := gob.NewDecoder(r)
+	for {
+		var e snapshotEntry
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if e.Remaining <= 0 {
+			continue
+		}
+		v, err := h.codec.Unmarshal(e.Value)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		entry := &val{t: now.Add(e.Remaining), v: v, ttl: e.TTL, created: now.Add(-e.Age), cost: e.Cost, tags: e.Tags, absent: e.Absent}
+
+		h.mu.Lock()
+		if h.closed() {
+			h.mu.Unlock()
+			return CACHE_CLOSED
+		}
+		err = h.put(e.Key, entry)
+		h.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (h *ObjCache) loadSnapshotFile() {
+	f, err := os.Open(h.snapfile)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	defer f.Close()
+	err = h.Restore(f)
+	if err != nil {
+		log.Printf("cache: restoring snapshot %s failed: %s", h.snapfile, err)
+	}
+}
+
+func (h *ObjCache) writeSnapshotFile() error {
+	tmp, err := os.CreateTemp(filepath.Dir(h.snapfile), filepath.Base(h.snapfile)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = h.Snapshot(tmp)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	cerr := tmp.Close()
+	if err != nil {
+		return err
+	}
+	if cerr != nil {
+		return cerr
+	}
+	return os.Rename(tmp.Name(), h.snapfile)
+}
+
+// snapshotLoop writes the snapshot file every snapinterval, and once more when the cache closes
+func (h *ObjCache) snapshotLoop() {
+	go func() {
+		defer close(h.snapdone)
+		ticker := time.NewTicker(h.snapinterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := h.writeSnapshotFile(); err != nil {
+					log.Printf("cache: writing snapshot %s failed: %s", h.snapfile, err)
+				}
+			case <-h.ctx.Done():
+				if err := h.writeSnapshotFile(); err != nil {
+					log.Printf("cache: writing snapshot %s failed: %s", h.snapfile, err)
+				}
+				return
+			}
+		}
+	}()
+}
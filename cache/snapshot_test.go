@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	c := NewObjCache(5)
+	defer c.Close()
+
+	c.Insert("a", 1, time.Second)
+	c.Insert("b", "two", time.Millisecond*200)
+	c.Insert("c", 3, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	var buf bytes.Buffer
+	assert.Nil(t, c.Snapshot(&buf), "snapshot failed")
+
+	r := NewObjCache(5)
+	defer r.Close()
+	assert.Nil(t, r.Restore(&buf), "restore failed")
+
+	v, err := r.Get("a")
+	assert.Nil(t, err, "restore lost a record")
+	assert.Equal(t, 1, v, "restore changed a value")
+	v, err = r.Get("b")
+	assert.Nil(t, err, "restore lost a record")
+	assert.Equal(t, "two", v, "restore changed a value")
+	_, err = r.Get("c")
+	assert.Equal(t, NOT_FOUND, err, "restore loaded an expired record")
+
+	r.mu.RLock()
+	remaining := r.c["b"].t.Sub(time.Now())
+	r.mu.RUnlock()
+	assert.True(t, remaining > 0 && remaining <= time.Millisecond*200, "restore didn't keep the remaining ttl")
+}
+
+func TestSnapshotJSONCodec(t *testing.T) {
+	type user struct {
+		Name string
+	}
+	codec := JSONCodec{New: func() interface{} { return &user{} }}
+
+	c := NewObjCache(5, WithCodec(codec))
+	defer c.Close()
+	c.Insert("a", user{Name: "dustin"}, time.Second)
+
+	var buf bytes.Buffer
+	assert.Nil(t, c.Snapshot(&buf), "snapshot failed")
+
+	r := NewObjCache(5, WithCodec(codec))
+	defer r.Close()
+	assert.Nil(t, r.Restore(&buf), "restore failed")
+
+	v, err := r.Get("a")
+	assert.Nil(t, err, "restore lost a record")
+	assert.Equal(t, &user{Name: "dustin"}, v, "json codec didn't decode into the New type")
+}
+
+func TestSnapshotFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snap")
+
+	c := NewObjCache(5, SnapshotFile(path, time.Hour))
+	c.Insert("a", 1, time.Minute)
+	c.Close()
+
+	r := NewObjCache(5, SnapshotFile(path, time.Hour))
+	defer r.Close()
+	v, err := r.Get("a")
+	assert.Nil(t, err, "cache didn't warm start from the snapshot file")
+	assert.Equal(t, 1, v, "warm start changed a value")
+}
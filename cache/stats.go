@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a point in time view of a cache's counters. Counters are cumulative
+// from when the cache was created.
+type Stats struct {
+	Hits        int64
+	Misses      int64
+	Inserts     int64
+	Rejections  int64 // inserts that returned CACHEFULL
+	Expirations int64
+	Evictions   int64
+	Size        int
+
+	Loads       int64
+	LoadErrors  int64
+	LoadLatency time.Duration // total time spent in loaders
+}
+
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// AvgLoadLatency is the mean time a loader took
+func (s Stats) AvgLoadLatency() time.Duration {
+	if s.Loads == 0 {
+		return 0
+	}
+	return s.LoadLatency / time.Duration(s.Loads)
+}
+
+// Metrics is implemented by adapters for metrics libraries. Counters are cumulative
+// and should be exported as such, gauges can go up and down.
+type Metrics interface {
+	Counter(name string, value int64)
+	Gauge(name string, value float64)
+}
+
+// StatsSource is anything that can report cache stats, both caches implement it.
+type StatsSource interface {
+	Stats() Stats
+}
+
+// Export pushes every stat to m. Call it from a scrape handler so the values are
+// current, or on a ticker for push based systems.
+func (s Stats) Export(m Metrics) {
+	m.Counter("hits", s.Hits)
+	m.Counter("misses", s.Misses)
+	m.Counter("inserts", s.Inserts)
+	m.Counter("rejections", s.Rejections)
+	m.Counter("expirations", s.Expirations)
+	m.Counter("evictions", s.Evictions)
+	m.Counter("loads", s.Loads)
+	m.Counter("load_errors", s.LoadErrors)
+	m.Gauge("size", float64(s.Size))
+	m.Gauge("hit_ratio", s.HitRatio())
+	m.Gauge("load_latency_seconds", s.AvgLoadLatency().Seconds())
+}
+
+// counters are updated with atomics so reads don't have to take the cache lock
+type counters struct {
+	hits        int64
+	misses      int64
+	inserts     int64
+	rejections  int64
+	expirations int64
+	evictions   int64
+	loads       int64
+	loaderrs    int64
+	loadnanos   int64
+}
+
+func (c *counters) hit(ok bool) {
+	if ok {
+		atomic.AddInt64(&c.hits, 1)
+		return
+	}
+	atomic.AddInt64(&c.misses, 1)
+}
+
+func (c *counters) insert(err error) {
+	if err == nil {
+		atomic.AddInt64(&c.inserts, 1)
+	} else if err == CACHEFULL {
+		atomic.AddInt64(&c.rejections, 1)
+	}
+}
+
+func (c *counters) load(d time.Duration, err error) {
+	atomic.AddInt64(&c.loads, 1)
+	atomic.AddInt64(&c.loadnanos, int64(d))
+	if err != nil {
+		atomic.AddInt64(&c.loaderrs, 1)
+	}
+}
+
+func (c *counters) stats(size int) Stats {
+	return Stats{
+		Hits:        atomic.LoadInt64(&c.hits),
+		Misses:      atomic.LoadInt64(&c.misses),
+		Inserts:     atomic.LoadInt64(&c.inserts),
+		Rejections:  atomic.LoadInt64(&c.rejections),
+		Expirations: atomic.LoadInt64(&c.expirations),
+		Evictions:   atomic.LoadInt64(&c.evictions),
+		Size:        size,
+		Loads:       atomic.LoadInt64(&c.loads),
+		LoadErrors:  atomic.LoadInt64(&c.loaderrs),
+		LoadLatency: time.Duration(atomic.LoadInt64(&c.loadnanos)),
+	}
+}
+
+// GetOrLoad returns the value at k. On a miss it calls load, caches the result
+// for duration and returns it. Load errors are returned and nothing is cached,
+// except NOT_FOUND, which caches k as absent and returns ABSENT.
+// The value is returned even if the cache is too full to keep it.
+func (h *ObjCache) GetOrLoad(k string, duration time.Duration, load func(k string) (interface{}, error)) (interface{}, error) {
+	v, err := h.Get(k)
+	if err != NOT_FOUND {
+		return v, err
+	}
+	start := time.Now()
+	v, err = load(k)
+	h.counters.load(time.Since(start), err)
+	if err == NOT_FOUND {
+		h.SetAbsent(k)
+		return nil, ABSENT
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = h.Insert(k, v, duration)
+	if err == CACHE_CLOSED {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testMetrics map[string]float64
+
+func (m testMetrics) Counter(name string, value int64) { m[name] = float64(value) }
+func (m testMetrics) Gauge(name string, value float64) { m[name] = value }
+
+func TestStats(t *testing.T) {
+	c := NewObjCache(2, ScanRate(time.Millisecond*10))
+	defer c.Close()
+
+	c.Insert("a", 1, time.Second)
+	c.Insert("b", 2, time.Millisecond*5)
+	c.Insert("c", 3, time.Second)
+	c.Get("a")
+	c.Get("a")
+	c.Get("z")
+	c.Check("z")
+	time.Sleep(time.Millisecond * 30)
+
+	s := c.Stats()
+	assert.Equal(t, int64(2), s.Hits, "wrong hit count")
+	assert.Equal(t, int64(2), s.Misses, "wrong miss count")
+	assert.Equal(t, int64(2), s.Inserts, "wrong insert count")
+	assert.Equal(t, int64(1), s.Rejections, "wrong CACHEFULL count")
+	assert.Equal(t, int64(1), s.Expirations, "wrong expiration count")
+	assert.Equal(t, 1, s.Size, "wrong size")
+	assert.Equal(t, 0.5, s.HitRatio(), "wrong hit ratio")
+
+	m := testMetrics{}
+	s.Export(m)
+	assert.Equal(t, float64(2), m["hits"], "export didn't report hits")
+	assert.Equal(t, float64(1), m["size"], "export didn't report size")
+}
+
+func TestGetOrLoad(t *testing.T) {
+	c := NewObjCache(2)
+	defer c.Close()
+
+	calls := 0
+	load := func(k string) (interface{}, error) {
+		calls++
+		time.Sleep(time.Millisecond)
+		return k + "!", nil
+	}
+
+	v, err := c.GetOrLoad("a", time.Second, load)
+	assert.Nil(t, err, "load failed")
+	assert.Equal(t, "a!", v, "load returned the wrong value")
+	v, err = c.GetOrLoad("a", time.Second, load)
+	assert.Nil(t, err, "get of a loaded value failed")
+	assert.Equal(t, "a!", v, "loaded value wasn't cached")
+	assert.Equal(t, 1, calls, "loader called for a cached value")
+
+	loaderr := errors.New("backend down")
+	_, err = c.GetOrLoad("b", time.Second, func(k string) (interface{}, error) { return nil, loaderr })
+	assert.Equal(t, loaderr, err, "loader error wasn't returned")
+	assert.False(t, c.Check("b"), "failed load was cached")
+
+	s := c.Stats()
+	assert.Equal(t, int64(2), s.Loads, "wrong load count")
+	assert.Equal(t, int64(1), s.LoadErrors, "wrong load error count")
+	assert.True(t, s.LoadLatency >= time.Millisecond, "load latency wasn't recorded")
+}
+
+func TestTimeoutCache_Stats(t *testing.T) {
+	c := NewTimeoutCache(1)
+	defer c.Close()
+
+	c.Insert("a", time.Second)
+	c.Insert("b", time.Second)
+	c.Check("a")
+	c.Check("b")
+
+	s := c.Stats()
+	assert.Equal(t, int64(1), s.Hits, "wrong hit count")
+	assert.Equal(t, int64(1), s.Misses, "wrong miss count")
+	assert.Equal(t, int64(1), s.Inserts, "wrong insert count")
+	assert.Equal(t, int64(1), s.Rejections, "wrong CACHEFULL count")
+	assert.Equal(t, 1, s.Size, "wrong size")
+}
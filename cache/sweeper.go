@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// sweeper runs sweep every scan rate until ctx is done. A send on rescan makes it
+// reread the rate, which is how SetScanRate takes effect on a running cache.
+type sweeper struct {
+	ctx    context.Context
+	rate   func() time.Duration
+	rescan chan struct{}
+	done   chan struct{}
+}
+
+func newSweeper(ctx context.Context, rate func() time.Duration) *sweeper {
+	return &sweeper{
+		ctx:    ctx,
+		rate:   rate,
+		rescan: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+func (s *sweeper) run(sweep func()) {
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.rate())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				sweep()
+			case <-s.rescan:
+				ticker.Reset(s.rate())
+			case <-s.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// reschedule is non-blocking, a pending rescan already covers this one
+func (s *sweeper) reschedule() {
+	select {
+	case s.rescan <- struct{}{}:
+	default:
+	}
+}
+
+func (s *sweeper) wait() {
+	<-s.done
+}
@@ -1,7 +1,6 @@
 package cache
 
 import (
-	"sync"
 	"time"
 
 	"errors"
@@ -10,89 +9,44 @@ import (
 var CACHEFULL = errors.New("could not insert, the cache is full")
 var ALREADY_EXISTS = errors.New("this key already exists")
 var EMPTY_RECORD = errors.New("the value for this key is empty")
+var NOT_FOUND = errors.New("this key is not in the cache")
+var CACHE_CLOSED = errors.New("this cache is closed")
+var ABSENT = errors.New("this key is cached as missing")
 
 type TimeoutCache struct {
-	c          map[string]time.Time
-	records    int
-	maxrecords int
-	mu         sync.RWMutex
-	scanRate   time.Duration
+	core
 }
 
 func NewTimeoutCache(maxrecords int, opts ...CacheOption) *TimeoutCache {
-	if maxrecords < 1 {
-		maxrecords = 1
-	}
-
-	t := &TimeoutCache{
-		c:          make(map[string]time.Time),
-		records:    0,
-		maxrecords: maxrecords,
-		scanRate:   time.Second * 30,
-	}
+	t := &TimeoutCache{}
+	t.init(maxrecords)
 
 	for _, opt := range opts {
 		opt(t)
 	}
 
-	go func() {
-		ticker := time.NewTicker(t.scanRate)
-		for range ticker.C {
-			t.mu.Lock()
-			for k, v := range t.c {
-				if v.Before(time.Now()) {
-					delete(t.c, k)
-					t.records--
-				}
-			}
-			t.mu.Unlock()
-		}
-	}()
+	t.start()
 
 	return t
 }
 
-func (t *TimeoutCache) SetScanRate(duration time.Duration) {
-	t.scanRate = duration
-}
-
-func (t *TimeoutCache) Check(k string) bool {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	_, ok := t.c[k]
-	return ok
-}
-
+// Insert caches s for duration. It returns ALREADY_EXISTS if s is cached.
 func (t *TimeoutCache) Insert(s string, duration time.Duration) error {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	if len(t.c) >= t.maxrecords {
-		return CACHEFULL
-	}
-	expiration := time.Now().Add(duration)
-
-	_, ok := t.c[s]
-	if ok {
-		return ALREADY_EXISTS
-	}
-	t.c[s] = expiration
-	t.records++
-	return nil
+	return t.insert(s, t.newVal(nil, duration, nil))
 }
 
-func (t *TimeoutCache) Uncache(s string) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	delete(t.c, s)
-	t.records--
+// Set caches s for duration, restarting the timeout if s is already cached.
+func (t *TimeoutCache) Set(s string, duration time.Duration) error {
+	return t.set(s, t.newVal(nil, duration, nil))
 }
 
-func (t *TimeoutCache) UncacheMany(s []string) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	for _, k := range s {
-		delete(t.c, k)
-		t.records--
-	}
+// InsertIfAbsent caches s unless it's already cached, and reports whether it was.
+func (t *TimeoutCache) InsertIfAbsent(s string, duration time.Duration) (bool, error) {
+	_, loaded, err := t.insertIfAbsent(s, t.newVal(nil, duration, nil))
+	return loaded, err
+}
 
+// Replace restarts the timeout for s only if it's cached, otherwise it returns NOT_FOUND.
+func (t *TimeoutCache) Replace(s string, duration time.Duration) error {
+	return t.replace(s, t.newVal(nil, duration, nil))
 }
@@ -94,3 +94,69 @@ func TestTimeoutCache_ScanRate(t *testing.T) {
 	time.Sleep(time.Millisecond * 120)
 	assert.False(t, c.Check("a"), "check returned true for evicted value")
 }
+
+func TestTimeoutCache_Sliding(t *testing.T) {
+	c := NewTimeoutCache(2, ScanRate(time.Millisecond*10), Sliding(time.Millisecond*150))
+
+	c.Insert("a", time.Millisecond*60)
+	c.Insert("b", time.Millisecond*60)
+
+	for i := 0; i < 5; i++ {
+		time.Sleep(time.Millisecond * 20)
+		assert.True(t, c.Check("a"), "check returned false for a value that's being read")
+	}
+	assert.False(t, c.Check("b"), "check returned true for a value that wasn't read")
+
+	time.Sleep(time.Millisecond * 70)
+	assert.False(t, c.Check("a"), "check returned true for a value past its max lifetime")
+}
+
+func TestTimeoutCache_Close(t *testing.T) {
+	c := NewTimeoutCache(2)
+
+	assert.Nil(t, c.Insert("a", time.Second), "insert failed on open cache")
+	c.Close()
+
+	assert.Equal(t, CACHE_CLOSED, c.Insert("b", time.Second), "insert after close didn't return CACHE_CLOSED")
+	assert.False(t, c.Check("a"), "check after close returned true")
+}
+
+func TestTimeoutCache_SetScanRate(t *testing.T) {
+	c := NewTimeoutCache(2)
+	defer c.Close()
+
+	c.Insert("a", time.Millisecond*20)
+	c.SetScanRate(time.Millisecond * 10)
+	time.Sleep(time.Millisecond * 50)
+
+	c.mu.RLock()
+	n := len(c.c)
+	c.mu.RUnlock()
+	assert.Equal(t, 0, n, "changing the scan rate on a running cache had no effect")
+}
+
+func TestTimeoutCache_InsertSemantics(t *testing.T) {
+	c := NewTimeoutCache(2)
+	defer c.Close()
+
+	assert.Nil(t, c.Insert("a", time.Second), "insert failed")
+	assert.Equal(t, ALREADY_EXISTS, c.Insert("a", time.Second), "duplicate insert didn't return ALREADY_EXISTS")
+	assert.Nil(t, c.Set("a", time.Second), "set failed on an existing key")
+
+	loaded, err := c.InsertIfAbsent("a", time.Second)
+	assert.Nil(t, err, "insert if absent failed")
+	assert.True(t, loaded, "insert if absent didn't report the existing key")
+	loaded, err = c.InsertIfAbsent("b", time.Second)
+	assert.Nil(t, err, "insert if absent failed")
+	assert.False(t, loaded, "insert if absent reported a missing key as existing")
+
+	assert.Equal(t, ALREADY_EXISTS, c.Insert("b", time.Second), "full cache didn't report the duplicate first")
+	assert.Equal(t, NOT_FOUND, c.Replace("c", time.Second), "replace inserted a missing key")
+	assert.Nil(t, c.Replace("b", time.Second), "replace failed on an existing key")
+	assert.Equal(t, 2, c.records, "record count is wrong")
+
+	c.Uncache("a")
+	c.Uncache("a")
+	c.UncacheMany([]string{"a", "z"})
+	assert.Equal(t, 1, c.records, "uncache of missing keys changed the record count")
+}
@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"time"
+)
+
+// TwoTier puts an ObjCache in front of a larger Store. Reads that miss the ObjCache
+// fall through to the Store and promote what they find. Writes go to both tiers.
+// Values are encoded for the Store with the ObjCache's codec, see WithCodec.
+type TwoTier struct {
+	l1 *ObjCache
+	l2 Store
+}
+
+func NewTwoTier(l1 *ObjCache, l2 Store) *TwoTier {
+	return &TwoTier{l1: l1, l2: l2}
+}
+
+// Get checks the ObjCache, then the Store. Values found in the Store are
+// promoted to the ObjCache for the rest of their ttl.
+func (t *TwoTier) Get(k string) (interface{}, error) {
+	v, err := t.l1.Get(k)
+	if err != NOT_FOUND {
+		return v, err
+	}
+	b, expires, err := t.l2.Get(k)
+	if err != nil {
+		return nil, err
+	}
+	remaining := time.Until(expires)
+	if remaining <= 0 {
+		return nil, NOT_FOUND
+	}
+	v, err = t.l1.codec.Unmarshal(b)
+	if err != nil {
+		return nil, err
+	}
+	// a full or closed ObjCache just means the value isn't promoted
+	t.l1.Set(k, v, remaining)
+	return v, nil
+}
+
+// Set writes v to the Store, then the ObjCache. CACHEFULL from the ObjCache is
+// not an error, the value is still in the Store.
+func (t *TwoTier) Set(k string, v interface{}, duration time.Duration) error {
+	b, err := t.l1.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	err = t.l2.Set(k, b, time.Now().Add(duration))
+	if err != nil {
+		return err
+	}
+	err = t.l1.Set(k, v, duration)
+	if err == CACHEFULL {
+		return nil
+	}
+	return err
+}
+
+// Uncache removes k from both tiers
+func (t *TwoTier) Uncache(k string) error {
+	t.l1.Uncache(k)
+	return t.l2.Delete(k)
+}
+
+// Close closes both tiers
+func (t *TwoTier) Close() error {
+	t.l1.Close()
+	return t.l2.Close()
+}
@@ -0,0 +1,45 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// CanceledError is submitted to a stage's Monitor, once, when the stage's
+// context is canceled. It unwraps to the context's error, so
+// errors.Is(err, context.Canceled) holds as well.
+type CanceledError struct {
+	Stage string
+	Err   error
+}
+
+func (e *CanceledError) Error() string {
+	return fmt.Sprintf("%s: canceled: %s", e.Stage, e.Err)
+}
+
+func (e *CanceledError) Unwrap() error {
+	return e.Err
+}
+
+func IsCanceled(err error) bool {
+	var c *CanceledError
+	return errors.As(err, &c)
+}
+
+func canceled(stage string, ctx context.Context) error {
+	return &CanceledError{Stage: stage, Err: ctx.Err()}
+}
+
+// cancelReport submits a stage's CanceledError once, however many of the
+// stage's goroutines see the cancellation
+type cancelReport struct {
+	once sync.Once
+}
+
+func (c *cancelReport) submit(m *Monitor, stage string, ctx context.Context) {
+	c.once.Do(func() {
+		m.SubmitErr(canceled(stage, ctx))
+	})
+}
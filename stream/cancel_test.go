@@ -0,0 +1,113 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingReader returns one record, then blocks in Read until released
+type blockingReader struct {
+	sent    bool
+	release chan struct{}
+}
+
+func (r *blockingReader) Read() ([]byte, error) {
+	if !r.sent {
+		r.sent = true
+		return []byte("x\n"), nil
+	}
+	<-r.release
+	return nil, io.EOF
+}
+
+func (r *blockingReader) Close() {}
+
+func (r *blockingReader) Name() string {
+	return "blocking"
+}
+
+func TestCancelBlockedRead(t *testing.T) {
+	ctx, canc := context.WithCancel(context.Background())
+	r := &blockingReader{release: make(chan struct{})}
+	defer close(r.release)
+
+	read, mon := NewRead(ctx, r, ChunkSize(1))
+	conv, _ := NewConvert(ctx, upper, []InChan{read.GetStream()})
+	assert.Equal(t, [][]byte{[]byte("X\n")}, <-conv.GetStream(), "the first record didn't make it through")
+
+	// Read is now blocked
+	canc()
+	closed := make(chan struct{})
+	go func() {
+		collect(conv.GetStream())
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("cancel didn't close the downstream channel")
+	}
+	assert.False(t, mon.GetSuccess(), "canceled read succeeded")
+	errs := monitorErrs(mon)
+	if assert.Len(t, errs, 1, "wrong errors") {
+		assert.True(t, IsCanceled(errs[0]), "cancel wasn't reported as a CanceledError")
+		assert.True(t, errors.Is(errs[0], context.Canceled), "CanceledError doesn't unwrap to context.Canceled")
+	}
+	select {
+	case <-read.Drained():
+		t.Error("read was drained while Read was still blocked")
+	default:
+	}
+}
+
+func TestCancelStages(t *testing.T) {
+	tests := []struct {
+		name  string
+		stage func(ctx context.Context, in []InChan) *Monitor
+	}{
+		{"read", func(ctx context.Context, in []InChan) *Monitor {
+			_, mon := NewRead(ctx, newSliceReader("a\n", "b\n"))
+			return mon
+		}},
+		{"convert", func(ctx context.Context, in []InChan) *Monitor {
+			c, mon := NewConvert(ctx, upper, in)
+			go collect(c.GetStream())
+			return mon
+		}},
+		{"filter", func(ctx context.Context, in []InChan) *Monitor {
+			f, mon := NewFilter(ctx, func([]byte) bool { return true }, in)
+			go collect(f.GetStream())
+			return mon
+		}},
+		{"flatmap", func(ctx context.Context, in []InChan) *Monitor {
+			f, mon := NewFlatMap(ctx, words, in)
+			go collect(f.GetStream())
+			return mon
+		}},
+		{"write", func(ctx context.Context, in []InChan) *Monitor {
+			_, mon := NewWrite(ctx, &bytes.Buffer{}, in)
+			return mon
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, canc := context.WithCancel(context.Background())
+			canc()
+			mon := tt.stage(ctx, []InChan{chunks("r", 100)})
+			assert.False(t, mon.GetSuccess(), "canceled stage succeeded")
+			var canceled int
+			for _, err := range monitorErrs(mon) {
+				if IsCanceled(err) {
+					canceled++
+				}
+			}
+			assert.Equal(t, 1, canceled, "the cancel wasn't reported once")
+		})
+	}
+}
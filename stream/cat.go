@@ -4,17 +4,73 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 )
 
 type CatOption func(*Cat) *Cat
 
+// ConcurrentConverters sets how many goroutines run fn, output order is kept
+func ConcurrentConverters(n int) CatOption {
+	return func(c *Cat) *Cat {
+		c.concurrentConverters = n
+		return c
+	}
+}
+
+// CatPolicy sets what happens to records fn fails on, the default is Skip.
+// Read and write errors always stop the cat.
+func CatPolicy(p ErrorPolicy) CatOption {
+	return func(c *Cat) *Cat {
+		c.policy = p
+		return c
+	}
+}
+
+// Resumable checkpoints progress to path at most every interval, and a
+// CatFiles of the same files with the same path picks up where it stopped.
+func Resumable(path string, interval time.Duration) CatOption {
+	return func(c *Cat) *Cat {
+		c.checkpoint = path
+		c.interval = interval
+		return c
+	}
+}
+
+// Sources sets how the paths passed to CatFiles are expanded, they may be
+// files, globs or directories, see Expand
+func Sources(opts ...ExpandOption) CatOption {
+	return func(c *Cat) *Cat {
+		c.expand = append(c.expand, opts...)
+		return c
+	}
+}
+
+// MaxOpenFiles bounds how many files are read at once, see MaxOpen. The
+// default is 1 so the output follows the order of the files, with more the
+// records of the files open at once are interleaved.
+func MaxOpenFiles(n int) CatOption {
+	return func(c *Cat) *Cat {
+		c.maxopen = n
+		return c
+	}
+}
+
 type Cat struct {
 	fn                   ConvertFn
 	absfilepath          []string
 	ctx                  context.Context
 	concurrentConverters int
+	policy               ErrorPolicy
+	checkpoint           string
+	interval             time.Duration
+	expand               []ExpandOption
+	maxopen              int
 }
 
+// CatFiles converts the records of absfilenames with fn and writes them to
+// stdout. Records fn fails on are logged and skipped, and don't make CatFiles
+// fail, unless a CatPolicy stops it. It returns an error if a file can't be
+// read, stdout can't be written, the CatPolicy stopped it or ctx was canceled.
 func CatFiles(fn ConvertFn, absfilenames []string, ctx context.Context, opts ...CatOption) error {
 	if len(absfilenames) == 0 {
 		return fmt.Errorf("no files passed to CatFiles")
@@ -24,47 +80,48 @@ func CatFiles(fn ConvertFn, absfilenames []string, ctx context.Context, opts ...
 		fn:                   fn,
 		ctx:                  ctx,
 		concurrentConverters: 1,
+		maxopen:              1,
 	}
 	for _, opt := range opts {
 		opt(rs)
 	}
 
-	rmons := make([]*Monitor, len(absfilenames))
-	rstreams := make([]InChan, len(absfilenames))
+	var cp *Checkpoint
+	if rs.checkpoint != "" {
+		var err error
+		cp, err = OpenCheckpoint(rs.checkpoint, rs.interval)
+		if err != nil {
+			return err
+		}
+	}
+
+	var p *Pipeline
 	if len(absfilenames) == 1 && absfilenames[0] == "stdin" {
-		read, mon := NewRead(NewFileReader(os.Stdin), ChunkSize(1024))
-		rstreams[0] = read.GetStream()
-		rmons[0] = mon
+		fileopts := []FileReaderOption{AutoDecompress()}
+		if cp != nil {
+			fileopts = append(fileopts, ResumeFrom(cp))
+		}
+		p = From(NewFileReader(os.Stdin, fileopts...))
 	} else {
-		for i, name := range absfilenames {
-			option, err := FileToRead(name)
-			if err != nil {
-				return err
-			}
-			read, mon := NewRead(NewFileReader(nil, option), ChunkSize(1024))
-			rstreams[i] = read.GetStream()
-			rmons[i] = mon
+		paths, err := Expand(absfilenames, rs.expand...)
+		if err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no files found in %v", absfilenames)
 		}
+		// files are opened as they're read, not all up front
+		p = FromFiles(paths, MaxOpen(rs.maxopen), FileOptions(AutoDecompress()))
 	}
 
-	// TODO: don't join if there's only one
-	readmon := JoinMonitors(rmons...)
-
-	convert, monc := NewConvert(rs.fn, rstreams)
-	_, monw := NewWrite(os.Stdout, []InChan{convert.GetStream()})
-
-	readmon.CancelOnErr(func() {
-		readmon.CancelRoutine()
-		monc.CancelRoutine()
-		monw.CancelRoutine()
-	})
-	monw.CancelOnErr(func() {
-		readmon.CancelRoutine()
-		monc.CancelRoutine()
-		monw.CancelRoutine()
-	})
-	monc.Log()
-
-	monw.GetSuccess()
-	return nil
+	// errors and stats are logged as they come, a long cat doesn't hold
+	// them until it's done
+	p.Log().
+		ReadOptions(ChunkSize(1024)).
+		Convert(rs.fn, Workers(rs.concurrentConverters), ConvertPolicy(rs.policy)).
+		To(os.Stdout)
+	if cp != nil {
+		p.Checkpoint(cp)
+	}
+	return p.Run(rs.ctx).Failure()
 }
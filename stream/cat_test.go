@@ -0,0 +1,86 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// catToFile runs CatFiles with stdout going to a file and returns what was written
+func catToFile(t *testing.T, fn ConvertFn, paths []string, opts ...CatOption) (string, error) {
+	t.Helper()
+	out, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	stdout := os.Stdout
+	os.Stdout = out
+	err = CatFiles(fn, paths, context.Background(), opts...)
+	os.Stdout = stdout
+	b, _ := os.ReadFile(out.Name())
+	return string(b), err
+}
+
+func TestCatFilesConcurrentConverters(t *testing.T) {
+	var in, want strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&in, "line%04d\n", i)
+		fmt.Fprintf(&want, "LINE%04d\n", i)
+	}
+	p := writeTemp(t, "in", in.String())
+
+	for _, n := range []int{1, 8} {
+		out, err := catToFile(t, jitter, []string{p}, ConcurrentConverters(n))
+		assert.Nil(t, err, "CatFiles failed")
+		assert.Equal(t, want.String(), out, "%v converters didn't keep order", n)
+	}
+}
+
+func TestCatFiles(t *testing.T) {
+	in := writeTemp(t, "in", "a\nbad\nb\n")
+	// files of several chunks each, newest first by name
+	dir := t.TempDir()
+	var sorted strings.Builder
+	for i, name := range []string{"c", "b", "a"} {
+		var lines strings.Builder
+		for j := 0; j < 20000; j++ {
+			fmt.Fprintf(&lines, "%s%05d\n", name, j)
+		}
+		p := filepath.Join(dir, name)
+		os.WriteFile(p, []byte(lines.String()), 0644)
+		mtime := time.Now().Add(time.Duration(i-3) * time.Hour)
+		os.Chtimes(p, mtime, mtime)
+		sorted.WriteString(lines.String())
+	}
+	tests := []struct {
+		name   string
+		paths  []string
+		opts   []CatOption
+		want   string
+		err    bool
+		policy bool
+	}{
+		{"skip", []string{in}, nil, "a\nb\n", false, false},
+		{"fail fast", []string{in}, []CatOption{CatPolicy(FailFast())}, "", true, true},
+		{"missing file", []string{in, filepath.Join(t.TempDir(), "missing")}, nil, "", true, false},
+		{"no files", nil, nil, "", true, false},
+		{"sorted files keep their order", []string{dir}, []CatOption{Sources(SortBy(ByMtime))}, sorted.String(), false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := catToFile(t, rejectBad, tt.paths, tt.opts...)
+			assert.Equal(t, tt.err, err != nil, "wrong error: %v", err)
+			assert.Equal(t, tt.policy, IsPolicyError(err), "CatFiles didn't fail with the policy's error")
+			if !tt.err {
+				assert.Equal(t, tt.want, out, "wrong output")
+			}
+		})
+	}
+}
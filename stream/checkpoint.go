@@ -0,0 +1,161 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Progress is how far into a file a pipeline has durably got
+type Progress struct {
+	Offset  int64 `json:"offset"`
+	Records int64 `json:"records"`
+	Done    bool  `json:"done"`
+}
+
+// Checkpoint records per file Progress for a pipeline so a failed run can
+// resume, see ReadCheckpoint, ResumeFrom and WriteCheckpoint.
+//
+// Reads register the position after each chunk they send and the sink
+// acknowledges each chunk once it's written. At most every interval the
+// checkpoint pauses the reads until everything they've sent has been
+// acknowledged, flushes the sink and commits. Progress is never ahead of
+// what the sink has written. This relies on every stage in between sending
+// one chunk for each chunk it receives, which the stages in this package do.
+type Checkpoint struct {
+	path     string
+	interval time.Duration
+
+	mu        sync.Mutex
+	committed map[string]Progress
+	pending   map[string]Progress
+	sent      int64
+	acked     int64
+	last      time.Time
+	// resume is non nil while reads are paused for a commit
+	resume chan struct{}
+}
+
+// OpenCheckpoint loads the progress committed to path, if there is any
+func OpenCheckpoint(path string, interval time.Duration) (*Checkpoint, error) {
+	c := &Checkpoint{
+		path:      path,
+		interval:  interval,
+		committed: make(map[string]Progress),
+		pending:   make(map[string]Progress),
+		last:      time.Now(),
+	}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(b, &c.committed)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Checkpoint) Progress(name string) (Progress, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.committed[name]
+	return p, ok
+}
+
+// send registers a chunk ending at p, it blocks while a commit is waiting
+// for the sink to catch up
+func (c *Checkpoint) send(ctx context.Context, name string, p Progress) error {
+	for {
+		c.mu.Lock()
+		if c.resume == nil {
+			c.sent++
+			c.pending[name] = p
+			c.mu.Unlock()
+			return nil
+		}
+		resume := c.resume
+		c.mu.Unlock()
+
+		select {
+		case <-resume:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ack is called by the sink after each chunk it has written. flush makes
+// what the sink has written durable before a commit.
+func (c *Checkpoint) ack(flush func() error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.acked++
+	if c.resume == nil {
+		if time.Since(c.last) < c.interval {
+			return nil
+		}
+		c.resume = make(chan struct{})
+	}
+	if c.acked < c.sent {
+		return nil
+	}
+	return c.commit(flush)
+}
+
+// finish commits once the sink's inputs are done
+func (c *Checkpoint) finish(flush func() error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.acked < c.sent {
+		return nil
+	}
+	return c.commit(flush)
+}
+
+func (c *Checkpoint) commit(flush func() error) error {
+	defer func() {
+		if c.resume != nil {
+			close(c.resume)
+			c.resume = nil
+		}
+		c.last = time.Now()
+	}()
+	if flush != nil {
+		err := flush()
+		if err != nil {
+			return err
+		}
+	}
+	for name, p := range c.pending {
+		c.committed[name] = p
+	}
+	return c.save()
+}
+
+func (c *Checkpoint) save() error {
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = json.NewEncoder(tmp).Encode(c.committed)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	cerr := tmp.Close()
+	if err != nil {
+		return err
+	}
+	if cerr != nil {
+		return cerr
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
@@ -0,0 +1,169 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	// every record is 9 bytes once written, rec00000\n to rec04999\n
+	const records = 5000
+	var lines, quoted, frames bytes.Buffer
+	fw := NewFrameWriter(&frames, Fixed32)
+	for i := 0; i < records; i++ {
+		fmt.Fprintf(&lines, "rec%05d\n", i)
+		fmt.Fprintf(&quoted, "\"rec%05d\"\n", i)
+		fw.Write([]byte(fmt.Sprintf("rec%05d\n", i)))
+	}
+	fw.Close()
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write(lines.Bytes())
+	gw.Close()
+
+	tests := []struct {
+		name   string
+		in     []byte
+		reader func(f *FileReader) RecordReader
+	}{
+		{"lines", lines.Bytes(), nil},
+		{"gzip", gz.Bytes(), nil},
+		{"csv", append([]byte("name\n"), lines.Bytes()...), func(f *FileReader) RecordReader { return NewCSVReader(f, CSVHeader()) }},
+		{"jsonl", quoted.Bytes(), func(f *FileReader) RecordReader { return NewJSONLReader(f) }},
+		{"frame", frames.Bytes(), func(f *FileReader) RecordReader { return NewFrameReader(f, Fixed32) }},
+	}
+	// unquote turns the JSONL records back into lines, so every format writes the same
+	unquote := func(b []byte) ([]byte, error) {
+		return append(bytes.Trim(bytes.TrimSpace(b), "\""), '\n'), nil
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "in")
+			os.WriteFile(path, tt.in, 0644)
+			cpath := filepath.Join(dir, "checkpoint")
+			open := func(cp *Checkpoint) RecordReader {
+				f := openFile(t, path, AutoDecompress(), ResumeFrom(cp))
+				if tt.reader == nil {
+					return f
+				}
+				return tt.reader(f)
+			}
+
+			// the first run stops at a bad record
+			cp, err := OpenCheckpoint(cpath, 0)
+			assert.Nil(t, err, "open checkpoint failed")
+			var first bytes.Buffer
+			res := From(open(cp)).
+				ReadOptions(ChunkSize(50)).
+				Convert(func(b []byte) ([]byte, error) {
+					if bytes.Contains(b, []byte("rec03210")) {
+						return nil, errors.New("bad record")
+					}
+					return unquote(b)
+				}, ConvertPolicy(FailFast()), Workers(4)).
+				To(&first).
+				Checkpoint(cp).
+				Run(context.Background())
+			assert.False(t, res.Success, "run with a bad record succeeded")
+
+			cp, err = OpenCheckpoint(cpath, 0)
+			assert.Nil(t, err, "reopen checkpoint failed")
+			p, ok := cp.Progress(path)
+			assert.True(t, ok, "nothing was committed")
+			assert.False(t, p.Done, "a failed read was committed done")
+			assert.True(t, p.Records > 0 && p.Records <= 3210, "committed %v records, past the bad one", p.Records)
+			assert.True(t, p.Offset > 0, "committed offset is 0")
+			committed := int(p.Records) * 9
+			if !assert.True(t, first.Len() >= committed, "committed more than was written") {
+				return
+			}
+
+			// the second run picks up after the last committed record
+			var second bytes.Buffer
+			res = From(open(cp)).Convert(unquote).To(&second).Checkpoint(cp).Run(context.Background())
+			assert.True(t, res.Success, "resumed run failed: %v", res.Err())
+			assert.Equal(t, lines.String(), first.String()[:committed]+second.String(), "resumed output doesn't line up")
+
+			cp, _ = OpenCheckpoint(cpath, 0)
+			p, _ = cp.Progress(path)
+			assert.True(t, p.Done, "finished read wasn't committed done")
+			assert.Equal(t, int64(records), p.Records, "wrong record count")
+		})
+	}
+}
+
+func TestCheckpointFlushesSink(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTemp(t, "in", strings.Repeat("record\n", 1000))
+	cp, _ := OpenCheckpoint(filepath.Join(dir, "checkpoint"), 0)
+
+	out, _ := os.Create(filepath.Join(dir, "out"))
+	defer out.Close()
+	sink := bufio.NewWriterSize(out, 1<<20)
+	n := 0
+	res := From(openFile(t, path, ResumeFrom(cp))).
+		ReadOptions(ChunkSize(10)).
+		Convert(func(b []byte) ([]byte, error) {
+			n++
+			if n == 500 {
+				return nil, errors.New("bad record")
+			}
+			return b, nil
+		}, ConvertPolicy(FailFast())).
+		To(sink).
+		Checkpoint(cp).
+		Run(context.Background())
+	assert.False(t, res.Success, "run with a bad record succeeded")
+
+	// the buffer is never flushed by the run itself, only by the commits
+	p, _ := cp.Progress(path)
+	written, _ := os.ReadFile(out.Name())
+	assert.True(t, p.Records > 0, "nothing was committed")
+	assert.True(t, int64(len(written)) >= p.Offset, "committed %v bytes but only %v reached the file", p.Offset, len(written))
+}
+
+func TestCheckpointEnd(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		reader func(f *FileReader) RecordReader
+		want   string
+		done   Progress
+	}{
+		{"undelimited last line", "a\nb\nc", nil, "a\nb\nc", Progress{Offset: 5, Records: 3, Done: true}},
+		{"json array", "[1, 2, 3]", func(f *FileReader) RecordReader { return NewJSONArrayReader(f) }, "", Progress{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTemp(t, "in", tt.in)
+			cp, _ := OpenCheckpoint(filepath.Join(t.TempDir(), "checkpoint"), 0)
+			var r RecordReader = openFile(t, path, ResumeFrom(cp))
+			if tt.reader != nil {
+				r = tt.reader(r.(*FileReader))
+			}
+			var out bytes.Buffer
+			res := From(r).To(&out).Checkpoint(cp).Run(context.Background())
+			p, _ := cp.Progress(path)
+			assert.Equal(t, tt.done, p, "wrong progress")
+			if !tt.done.Done {
+				// a json array can't be resumed, so it isn't read at all
+				assert.False(t, res.Success, "checkpointed read succeeded")
+				assert.True(t, strings.Contains(fmt.Sprint(res.Failure()), "can't be resumed"), "wrong failure: %v", res.Failure())
+				return
+			}
+			assert.True(t, res.Success, "run failed: %v", res.Failure())
+			assert.Equal(t, tt.want, out.String(), "wrong output")
+		})
+	}
+}
@@ -11,8 +11,26 @@ type CollectFn func(b []byte) error
 
 type CollectOption func(c *Collect)
 
+// CollectCheckpoint acknowledges each chunk to cp once fn has been called on
+// its records, see Checkpoint
+func CollectCheckpoint(cp *Checkpoint) CollectOption {
+	return func(c *Collect) {
+		c.cp = cp
+	}
+}
+
+// CollectPolicy sets what happens to records fn fails on, the default is Skip
+func CollectPolicy(p ErrorPolicy) CollectOption {
+	return func(c *Collect) {
+		c.policy = p
+	}
+}
+
 type Collect struct {
-	fn CollectFn
+	fn     CollectFn
+	policy ErrorPolicy
+	onerr  *errorHandler
+	cp     *Checkpoint
 
 	ctx    context.Context
 	canc   context.CancelFunc
@@ -22,8 +40,8 @@ type Collect struct {
 	stats chan string
 }
 
-func NewCollect(fn CollectFn, in <-chan [][]byte, opts ...CollectOption) *Collect {
-	ctx, canc := context.WithCancel(context.Background())
+func NewCollect(ctx context.Context, fn CollectFn, in <-chan [][]byte, opts ...CollectOption) *Collect {
+	ctx, canc := context.WithCancel(ctx)
 	c := &Collect{
 		fn: fn,
 
@@ -36,6 +54,10 @@ func NewCollect(fn CollectFn, in <-chan [][]byte, opts ...CollectOption) *Collec
 	for _, opt := range opts {
 		opt(c)
 	}
+	c.onerr = newErrorHandler("collect", c.policy, c.submitErr, func(err error) {
+		c.submitErr(err)
+		c.canc()
+	})
 
 	c.donewg.Add(1)
 	go func() {
@@ -50,22 +72,39 @@ func (c *Collect) collect(in <-chan [][]byte) {
 	success := 0
 	failed := 0
 	start := time.Now()
+	reported := false
 	for chunk := range in {
 
 		select {
 		case <-c.ctx.Done():
-			c.submitErr(fmt.Errorf("collectstream: canceled"))
+			if !reported {
+				c.submitErr(canceled("collectstream", c.ctx))
+				reported = true
+			}
 			continue
 		default:
 			for _, bytes := range chunk {
 				err := c.fn(bytes)
 				if err != nil {
-					c.submitErr(fmt.Errorf("collect: encountered error: %s on record: %s, skipping", err, string(bytes)))
 					failed++
+					c.onerr.failure(bytes, err)
 					continue
 				}
+				c.onerr.success()
 				success++
 			}
+			if c.cp != nil && !c.onerr.stopped() {
+				err := c.cp.ack(nil)
+				if err != nil {
+					c.submitErr(fmt.Errorf("collectstream: checkpoint failed: %s", err))
+				}
+			}
+		}
+	}
+	if c.cp != nil && c.ctx.Err() == nil {
+		err := c.cp.finish(nil)
+		if err != nil {
+			c.submitErr(fmt.Errorf("collectstream: checkpoint failed: %s", err))
 		}
 	}
 	c.submitStat(fmt.Sprintf("successful collect of %v messages; %v failed; in %s",
@@ -0,0 +1,53 @@
+package stream
+
+import (
+	"compress/gzip"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/klauspost/pgzip"
+)
+
+// A Compressor wraps a sink so everything written to it is compressed. Write
+// closes the returned writer once all of its input channels are finished.
+type Compressor func(w io.Writer) (io.WriteCloser, error)
+
+// GzipCompressor uses a compress/gzip level, e.g. gzip.BestSpeed
+func GzipCompressor(level int) Compressor {
+	return func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriterLevel(w, level)
+	}
+}
+
+// PgzipCompressor compresses blocks of blockSize bytes on up to blocks goroutines.
+// The output is ordinary gzip. Worth it for large outputs, where gzip is the bottleneck.
+func PgzipCompressor(level, blockSize, blocks int) Compressor {
+	return func(w io.Writer) (io.WriteCloser, error) {
+		z, err := pgzip.NewWriterLevel(w, level)
+		if err != nil {
+			return nil, err
+		}
+		err = z.SetConcurrency(blockSize, blocks)
+		if err != nil {
+			return nil, err
+		}
+		return z, nil
+	}
+}
+
+// ZstdCompressor takes a zstd level from 1 to 22, which is mapped to the
+// closest level the encoder supports.
+func ZstdCompressor(level int) Compressor {
+	return func(w io.Writer) (io.WriteCloser, error) {
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	}
+}
+
+// Compress wraps the sink in c. Output written this way can be read back
+// with the matching FileReader Decompress option, or AutoDecompress.
+func Compress(c Compressor) WriteOption {
+	return func(w *Write) *Write {
+		w.compress = c
+		return w
+	}
+}
@@ -0,0 +1,79 @@
+package stream
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// chunks sends n records in chunks of 10 on a closed channel
+func chunks(prefix string, n int) InChan {
+	ch := make(chan [][]byte, n/10+1)
+	var chunk [][]byte
+	for i := 0; i < n; i++ {
+		chunk = append(chunk, []byte(fmt.Sprintf("%s%04d\n", prefix, i)))
+		if len(chunk) == 10 {
+			ch <- chunk
+			chunk = nil
+		}
+	}
+	if len(chunk) > 0 {
+		ch <- chunk
+	}
+	close(ch)
+	return ch
+}
+
+// monitorErrs reads a monitor's errors until its routine is done
+func monitorErrs(m *Monitor) []error {
+	var errs []error
+	for err := range m.ReadErrors() {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+func TestCompress(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Compressor
+		ok   bool
+	}{
+		{"out.gz", GzipCompressor(gzip.BestSpeed), true},
+		{"out.pgz", PgzipCompressor(gzip.DefaultCompression, 1<<20, 4), true},
+		{"out.zst", ZstdCompressor(3), true},
+		{"bad.gz", GzipCompressor(42), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.name)
+			out, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, mon := NewWrite(context.Background(), out, []InChan{chunks("r", 2000)}, Compress(tt.c))
+			errs := monitorErrs(mon)
+			out.Close()
+			if !tt.ok {
+				assert.NotEmpty(t, errs, "write with a bad compressor didn't fail")
+				return
+			}
+			assert.Empty(t, errs, "compressed write failed")
+
+			// the compressor was closed, so the whole stream reads back
+			f := openFile(t, path, AutoDecompress())
+			defer f.Close()
+			records, err := readAll(f)
+			assert.Nil(t, err, "read back failed")
+			if assert.Len(t, records, 2000, "records lost") {
+				assert.Equal(t, "r0000\n", records[0], "wrong first record")
+				assert.Equal(t, "r1999\n", records[1999], "wrong last record")
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -23,21 +24,73 @@ type ConvertOption func(c *Convert) *Convert
 //	}
 //}
 
+// Workers runs n converters shared by all of the inputs. Chunks leave in
+// the order they arrived on their input unless Unordered is set.
+func Workers(n int) ConvertOption {
+	return func(c *Convert) *Convert {
+		if n > 0 {
+			c.workers = n
+		}
+		return c
+	}
+}
+
+// ConvertPolicy sets what happens to records fn fails on, the default is Skip
+func ConvertPolicy(p ErrorPolicy) ConvertOption {
+	return func(c *Convert) *Convert {
+		c.policy = p
+		return c
+	}
+}
+
+// Unordered emits chunks as soon as they're converted, for throughput
+// when downstream doesn't care about order.
+func Unordered() ConvertOption {
+	return func(c *Convert) *Convert {
+		c.unordered = true
+		return c
+	}
+}
+
 type Convert struct {
 	fn ConvertFn
 
+	workers   int
+	unordered bool
+	jobs      chan job
+	policy    ErrorPolicy
+	errs      *errorHandler
+
+	success int64
+	failed  int64
+
 	outgoing chan [][]byte
 
-	ctx     context.Context
-	donewg  sync.WaitGroup
-	monitor *Monitor
+	ctx       context.Context
+	cancelrep cancelReport
+	donewg    sync.WaitGroup
+	monitor   *Monitor
+}
+
+// a job is a chunk tagged with its position on its input
+type job struct {
+	seq     uint64
+	chunk   [][]byte
+	results chan<- result
+	done    *sync.WaitGroup
+}
+
+type result struct {
+	seq   uint64
+	chunk [][]byte
 }
 
-func NewConvert(fn ConvertFn, in []InChan, opts ...ConvertOption) (*Convert, *Monitor) {
-	ctx, canc := context.WithCancel(context.Background())
+func NewConvert(ctx context.Context, fn ConvertFn, in []InChan, opts ...ConvertOption) (*Convert, *Monitor) {
+	ctx, canc := context.WithCancel(ctx)
 
 	c := &Convert{
-		fn: fn,
+		fn:      fn,
+		workers: 1,
 
 		outgoing: make(chan [][]byte, 16),
 		ctx:      ctx,
@@ -45,56 +98,136 @@ func NewConvert(fn ConvertFn, in []InChan, opts ...ConvertOption) (*Convert, *Mo
 	for _, opt := range opts {
 		opt(c)
 	}
+	c.jobs = make(chan job, c.workers)
 
-	c.donewg.Add(len(in))
+	c.donewg.Add(1)
 	monitor := NewMonitor(&c.donewg, canc)
 
 	c.monitor = monitor
+	c.errs = newErrorHandler("converter", c.policy, func(err error) { monitor.SubmitErr(err) }, monitor.Fail)
 
-	for _, ch := range in {
-		go func() {
-			defer close(c.outgoing)
-			defer c.donewg.Done()
-			c.convert(ch)
-		}()
-	}
+	go c.run(in)
 
 	return c, monitor
 }
 
-func (c *Convert) convert(in <-chan [][]byte) {
-	success := 0
-	failed := 0
+func (c *Convert) run(in []InChan) {
+	defer c.donewg.Done()
 	start := time.Now()
 
+	var workerwg sync.WaitGroup
+	workerwg.Add(c.workers)
+	for i := 0; i < c.workers; i++ {
+		go func() {
+			defer workerwg.Done()
+			for j := range c.jobs {
+				j.results <- result{seq: j.seq, chunk: c.convert(j.chunk)}
+				j.done.Done()
+			}
+		}()
+	}
+
+	var inputwg sync.WaitGroup
+	inputwg.Add(len(in))
+	for _, ch := range in {
+		go func(ch InChan) {
+			defer inputwg.Done()
+			c.dispatch(ch)
+		}(ch)
+	}
+	inputwg.Wait()
+	close(c.jobs)
+	workerwg.Wait()
+	// one close for every input, closing per input panics with more than one
+	close(c.outgoing)
+
+	success, failed := atomic.LoadInt64(&c.success), atomic.LoadInt64(&c.failed)
+	c.monitor.SubmitStat(fmt.Sprintf("\nsuccessful convert of %v messages; %v messages failed; finished in %s",
+		success, failed, time.Since(start)))
+	if c.errs.ok() && c.ctx.Err() == nil {
+		c.monitor.SetSuccess(true)
+	}
+}
+
+// dispatch sequences the chunks of one input onto the shared workers and,
+// unless unordered, puts the results back in order. At most two chunks per
+// worker are in flight per input, which bounds the reorder buffer.
+func (c *Convert) dispatch(in <-chan [][]byte) {
+	window := make(chan struct{}, 2*c.workers)
+	results := make(chan result, c.workers)
+	var inflight sync.WaitGroup
+
+	reordered := make(chan struct{})
+	go func() {
+		defer close(reordered)
+		c.reorder(results, window)
+	}()
+
+	var seq uint64
 	for chunk := range in {
 		select {
 		case <-c.ctx.Done():
-			c.monitor.SubmitErr(fmt.Errorf("convertstream: canceled"))
+			c.cancelrep.submit(c.monitor, "convertstream", c.ctx)
 			continue
 		default:
-			bufs := make([][]byte, len(chunk))
-			i := 0
-			for _, bytes := range chunk {
-				buf, err := c.fn(bytes)
-				if err != nil {
-					c.monitor.SubmitErr(fmt.Errorf("converter: encountered error: %s on record: %s, skipping", err, string(bytes)))
-					failed++
-					continue
-				}
-				success++
-				bufs[i] = buf
-				i++
-			}
-			c.outgoing <- bufs[:i]
+			window <- struct{}{}
+			inflight.Add(1)
+			c.jobs <- job{seq: seq, chunk: chunk, results: results, done: &inflight}
+			seq++
 		}
 	}
-	c.monitor.SubmitStat(fmt.Sprintf("\nsuccessful convert of %v messages; %v messages failed; finished in %s",
-		success, failed, time.Since(start)))
-	if failed == 0 {
-		c.monitor.SetSuccess(true)
+	inflight.Wait()
+	close(results)
+	<-reordered
+}
+
+func (c *Convert) reorder(results <-chan result, window <-chan struct{}) {
+	var next uint64
+	pending := make(map[uint64][][]byte)
+	for r := range results {
+		if c.unordered {
+			if r.chunk != nil {
+				c.outgoing <- r.chunk
+			}
+			<-window
+			continue
+		}
+		pending[r.seq] = r.chunk
+		for {
+			chunk, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			if chunk != nil {
+				c.outgoing <- chunk
+			}
+			<-window
+			next++
+		}
 	}
+}
 
+func (c *Convert) convert(chunk [][]byte) [][]byte {
+	bufs := make([][]byte, len(chunk))
+	i := 0
+	for _, bytes := range chunk {
+		buf, err := c.fn(bytes)
+		if err != nil {
+			atomic.AddInt64(&c.failed, 1)
+			c.errs.failure(bytes, err)
+			continue
+		}
+		c.errs.success()
+		atomic.AddInt64(&c.success, 1)
+		bufs[i] = buf
+		i++
+	}
+	// a nil chunk is dropped, see errorHandler.stopped
+	if c.errs.stopped() {
+		return nil
+	}
+	return bufs[:i]
 }
 
 // Called by consumers. The works as a demux or fanout if called multiple times. If a copy to
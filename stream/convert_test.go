@@ -0,0 +1,75 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func upper(b []byte) ([]byte, error) {
+	return bytes.ToUpper(b), nil
+}
+
+// jitter converts slowly and at random, so workers finish out of order
+func jitter(b []byte) ([]byte, error) {
+	time.Sleep(time.Duration(rand.Intn(200)) * time.Microsecond)
+	return upper(b)
+}
+
+// collect reads a stage's output until it's closed
+func collect(out <-chan [][]byte) []string {
+	var records []string
+	for chunk := range out {
+		for _, b := range chunk {
+			records = append(records, string(b))
+		}
+	}
+	return records
+}
+
+func TestConvertWorkers(t *testing.T) {
+	tests := []struct {
+		name    string
+		inputs  []string
+		opts    []ConvertOption
+		ordered bool
+	}{
+		{"one worker", []string{"a"}, nil, true},
+		{"workers", []string{"a"}, []ConvertOption{Workers(8)}, true},
+		{"unordered", []string{"a"}, []ConvertOption{Workers(8), Unordered()}, false},
+		{"inputs", []string{"a", "b", "c"}, []ConvertOption{Workers(4)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var in []InChan
+			for _, prefix := range tt.inputs {
+				in = append(in, chunks(prefix, 300))
+			}
+			c, _ := NewConvert(context.Background(), jitter, in, tt.opts...)
+
+			// each input keeps its own order, unless unordered
+			got := map[byte][]string{}
+			for _, rec := range collect(c.GetStream()) {
+				got[rec[0]] = append(got[rec[0]], rec)
+			}
+			assert.Len(t, got, len(tt.inputs), "wrong inputs")
+			for _, prefix := range tt.inputs {
+				var want []string
+				for i := 0; i < 300; i++ {
+					want = append(want, fmt.Sprintf("%s%04d\n", bytes.ToUpper([]byte(prefix)), i))
+				}
+				records := got[want[0][0]]
+				if !tt.ordered {
+					sort.Strings(records)
+				}
+				assert.Equal(t, want, records, "input %s lost records or is out of order", prefix)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package stream
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type CSVOption func(c *CSVReader) *CSVReader
+
+// CSVHeader treats the first record as a header. It isn't emitted, see Header.
+func CSVHeader() CSVOption {
+	return func(c *CSVReader) *CSVReader {
+		c.hasHeader = true
+		return c
+	}
+}
+
+// Comma sets the field separator, the default is ','
+func Comma(r rune) CSVOption {
+	return func(c *CSVReader) *CSVReader {
+		c.comma = r
+		return c
+	}
+}
+
+// CSVReader reads RFC 4180 records, so quoted fields may contain the
+// delimiter and newlines. Each record is emitted as a single CSV encoded
+// record terminated by '\n'; SplitCSV turns it back into fields.
+type CSVReader struct {
+	f     *FileReader
+	r     *csv.Reader
+	comma rune
+	err   error
+
+	hasHeader bool
+	header    []string
+
+	// base is the FileReader's offset when the csv.Reader started reading
+	base int64
+}
+
+func NewCSVReader(f *FileReader, opts ...CSVOption) *CSVReader {
+	c := &CSVReader{
+		f:     f,
+		comma: ',',
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	src, err := f.source()
+	if err != nil {
+		c.err = err
+		return c
+	}
+	c.r = csv.NewReader(src)
+	c.r.Comma = c.comma
+	c.base = f.Offset()
+
+	// a read resumed from a Checkpoint starts past the header
+	if c.hasHeader && c.base == 0 {
+		c.header, c.err = c.r.Read()
+	}
+	return c
+}
+
+// Offset is where the last record read ends, csv.Reader may have buffered
+// past it
+func (c *CSVReader) Offset() int64 {
+	if c.r == nil {
+		return c.base
+	}
+	return c.base + c.r.InputOffset()
+}
+
+// Header is nil unless the reader was created with CSVHeader. It's nil for a
+// reader resumed from a Checkpoint too, the header isn't read again.
+func (c *CSVReader) Header() []string {
+	return c.header
+}
+
+func (c *CSVReader) Read() ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	fields, err := c.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	return encodeCSV(fields, c.comma)
+}
+
+func (c *CSVReader) Close() {
+	c.f.Close()
+}
+
+func (c *CSVReader) Name() string {
+	return c.f.Name()
+}
+
+// SplitCSV parses a record emitted by a CSVReader
+func SplitCSV(record []byte, comma rune) ([]string, error) {
+	r := csv.NewReader(bytes.NewReader(record))
+	r.Comma = comma
+	return r.Read()
+}
+
+func encodeCSV(fields []string, comma rune) ([]byte, error) {
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	w.Comma = comma
+	err := w.Write(fields)
+	if err != nil {
+		return nil, err
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return []byte(sb.String()), nil
+}
+
+type CSVWriterOption func(c *CSVWriter) *CSVWriter
+
+// CSVWriteHeader writes fields as the first record
+func CSVWriteHeader(fields ...string) CSVWriterOption {
+	return func(c *CSVWriter) *CSVWriter {
+		c.header = fields
+		return c
+	}
+}
+
+// CSVWriteComma sets the field separator of both the incoming records and
+// the output, the default is ','
+func CSVWriteComma(r rune) CSVWriterOption {
+	return func(c *CSVWriter) *CSVWriter {
+		c.comma = r
+		return c
+	}
+}
+
+// CRLF ends records with \r\n as RFC 4180 specifies, the default is \n
+func CRLF() CSVWriterOption {
+	return func(c *CSVWriter) *CSVWriter {
+		c.crlf = true
+		return c
+	}
+}
+
+func CSVRecords(opts ...CSVWriterOption) RecordWriterFn {
+	return func(w io.Writer) RecordWriter {
+		return NewCSVWriter(w, opts...)
+	}
+}
+
+// CSVWriter takes records encoded as a CSVReader emits them, see JoinCSV,
+// and writes them as RFC 4180 CSV. A record that doesn't parse is an error.
+type CSVWriter struct {
+	w      io.Writer
+	csv    *csv.Writer
+	comma  rune
+	crlf   bool
+	header []string
+	wrote  bool
+}
+
+func NewCSVWriter(w io.Writer, opts ...CSVWriterOption) *CSVWriter {
+	c := &CSVWriter{
+		w:     w,
+		comma: ',',
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.csv = csv.NewWriter(w)
+	c.csv.Comma = c.comma
+	c.csv.UseCRLF = c.crlf
+	return c
+}
+
+func (c *CSVWriter) Write(record []byte) error {
+	if !c.wrote && c.header != nil {
+		err := c.csv.Write(c.header)
+		if err != nil {
+			return err
+		}
+	}
+	c.wrote = true
+	fields, err := SplitCSV(record, c.comma)
+	if err != nil {
+		return fmt.Errorf("%s: %s", c.Name(), err)
+	}
+	return c.csv.Write(fields)
+}
+
+func (c *CSVWriter) Flush() error {
+	c.csv.Flush()
+	return c.csv.Error()
+}
+
+// Close writes the header if no records were written, so the output is
+// still a valid CSV file
+func (c *CSVWriter) Close() error {
+	if !c.wrote && c.header != nil {
+		err := c.csv.Write(c.header)
+		if err != nil {
+			return err
+		}
+	}
+	c.csv.Flush()
+	return c.csv.Error()
+}
+
+func (c *CSVWriter) Name() string {
+	return sinkName(c.w)
+}
+
+// JoinCSV encodes fields as a single record, the inverse of SplitCSV
+func JoinCSV(fields []string, comma rune) ([]byte, error) {
+	return encodeCSV(fields, comma)
+}
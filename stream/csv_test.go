@@ -0,0 +1,62 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVReader(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		comma  rune
+		opts   []CSVOption
+		header []string
+		want   [][]string
+		err    bool
+	}{
+		{
+			name: "header and quoting",
+			in: "name,note\n" +
+				"a,\"comma, inside\"\n" +
+				"b,\"line one\nline two\"\n" +
+				"c,\"\"\"quoted\"\"\"\n",
+			comma:  ',',
+			opts:   []CSVOption{CSVHeader()},
+			header: []string{"name", "note"},
+			want:   [][]string{{"a", "comma, inside"}, {"b", "line one\nline two"}, {"c", "\"quoted\""}},
+		},
+		{
+			name:  "tab separated",
+			in:    "a\tb c\n1\t2\n",
+			comma: '\t',
+			opts:  []CSVOption{Comma('\t')},
+			want:  [][]string{{"a", "b c"}, {"1", "2"}},
+		},
+		{
+			name:  "unterminated quote",
+			in:    "a,b\n1,\"unterminated\n",
+			comma: ',',
+			want:  [][]string{{"a", "b"}},
+			err:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewCSVReader(openFile(t, writeTemp(t, "in.csv", tt.in)), tt.opts...)
+			defer r.Close()
+			records, err := readAll(r)
+			assert.Equal(t, tt.err, err != nil, "wrong error: %v", err)
+			assert.Equal(t, tt.header, r.Header(), "wrong header")
+
+			var got [][]string
+			for _, rec := range records {
+				fields, err := SplitCSV([]byte(rec), tt.comma)
+				assert.Nil(t, err, "emitted record doesn't parse")
+				got = append(got, fields)
+			}
+			assert.Equal(t, tt.want, got, "wrong records")
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package stream
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+
+	"github.com/cyberdelia/lzo"
+	"github.com/klauspost/compress/zstd"
+)
+
+// A Decompressor wraps compressed input in a reader of the decompressed stream.
+// If the returned reader is an io.Closer, FileReader.Close closes it.
+type Decompressor func(r io.Reader) (io.Reader, error)
+
+func GzipDecompressor(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+func ZstdDecompressor(r io.Reader) (io.Reader, error) {
+	d, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return zstdReadCloser{d}, nil
+}
+
+// zstd.Decoder.Close doesn't return an error, so it isn't an io.Closer
+type zstdReadCloser struct {
+	*zstd.Decoder
+}
+
+func (z zstdReadCloser) Close() error {
+	z.Decoder.Close()
+	return nil
+}
+
+func Bzip2Decompressor(r io.Reader) (io.Reader, error) {
+	return bzip2.NewReader(r), nil
+}
+
+func LzopDecompressor(r io.Reader) (io.Reader, error) {
+	return lzo.NewReader(r)
+}
+
+// Decompress sets the decompressor explicitly, e.g. for input without
+// magic bytes or a telling extension.
+func Decompress(d Decompressor) FileReaderOption {
+	return func(f *FileReader) *FileReader {
+		f.decompress = d
+		return f
+	}
+}
+
+func Gzip() FileReaderOption {
+	return Decompress(GzipDecompressor)
+}
+
+func Zstd() FileReaderOption {
+	return Decompress(ZstdDecompressor)
+}
+
+func Bzip2() FileReaderOption {
+	return Decompress(Bzip2Decompressor)
+}
+
+func Lzop() FileReaderOption {
+	return Decompress(LzopDecompressor)
+}
+
+// AutoDecompress detects gzip, zstd, bzip2 and lzop input by its magic bytes,
+// or failing that by file extension. Uncompressed input is read as is.
+func AutoDecompress() FileReaderOption {
+	return func(f *FileReader) *FileReader {
+		f.autodetect = true
+		return f
+	}
+}
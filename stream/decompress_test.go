@@ -0,0 +1,96 @@
+package stream
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTemp(t *testing.T, name, s string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(s), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func openFile(t *testing.T, path string, opts ...FileReaderOption) *FileReader {
+	t.Helper()
+	option, err := FileToRead(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewFileReader(nil, append([]FileReaderOption{option}, opts...)...)
+}
+
+// readAll reads r until it fails, reaching the end isn't a failure
+func readAll(r RecordReader) ([]string, error) {
+	var records []string
+	for {
+		b, err := r.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, string(b))
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write([]byte("a\nb\n"))
+	gw.Close()
+
+	var zst bytes.Buffer
+	zw, _ := zstd.NewWriter(&zst)
+	zw.Write([]byte("a\nb\n"))
+	zw.Close()
+
+	// printf 'a\nb\n' | bzip2
+	bz := []byte{
+		0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x3c, 0x85,
+		0x41, 0x12, 0x00, 0x00, 0x01, 0x41, 0x00, 0x00, 0x10, 0x30, 0x00, 0x20,
+		0x00, 0x30, 0xcc, 0x0c, 0x7a, 0x82, 0x71, 0x77, 0x24, 0x53, 0x85, 0x09,
+		0x03, 0xc8, 0x54, 0x11, 0x20,
+	}
+
+	tests := []struct {
+		name string
+		file string
+		in   []byte
+		opts []FileReaderOption
+		want string
+		err  bool
+	}{
+		{"explicit gzip", "in", gz.Bytes(), []FileReaderOption{Gzip()}, "a\nb\n", false},
+		// read raw, the newline ends the last record
+		{"gzip without an option", "in.gz", append(gz.Bytes(), '\n'), nil, gz.String() + "\n", false},
+		// detected by magic bytes, whatever the name
+		{"detected gzip", "gzip.log", gz.Bytes(), []FileReaderOption{AutoDecompress()}, "a\nb\n", false},
+		{"detected bzip2", "bzip2.log", bz, []FileReaderOption{AutoDecompress()}, "a\nb\n", false},
+		{"plain", "plain.log", []byte("a\nb\n"), []FileReaderOption{AutoDecompress()}, "a\nb\n", false},
+		// detected by extension
+		{"detected zstd", "in.zst", zst.Bytes(), []FileReaderOption{AutoDecompress()}, "a\nb\n", false},
+		{"corrupt gzip", "bad.gz", []byte{0x1f, 0x8b, 0, 0}, []FileReaderOption{AutoDecompress()}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := openFile(t, writeTemp(t, tt.file, string(tt.in)), tt.opts...)
+			defer f.Close()
+			records, err := readAll(f)
+			assert.Equal(t, tt.err, err != nil, "wrong error: %v", err)
+			assert.Equal(t, tt.want, strings.Join(records, ""), "wrong records")
+		})
+	}
+}
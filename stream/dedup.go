@@ -0,0 +1,108 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dustinevan/go-utils/cache"
+)
+
+type KeyFn func(b []byte) string
+
+type DedupOption func(d *Dedup) *Dedup
+
+// DedupKey sets how the dedup key is taken from a record, by default
+// it's the whole record.
+func DedupKey(fn KeyFn) DedupOption {
+	return func(d *Dedup) *Dedup {
+		d.keyfn = fn
+		return d
+	}
+}
+
+// Dedup drops records whose key the filter has already seen. With a
+// cache.RotatingBloom the filter's memory is fixed, at the cost of occasionally
+// dropping a record that wasn't a duplicate.
+type Dedup struct {
+	filter cache.DedupFilter
+	keyfn  KeyFn
+
+	outgoing chan [][]byte
+
+	ctx       context.Context
+	cancelrep cancelReport
+	donewg    sync.WaitGroup
+	monitor   *Monitor
+}
+
+func NewDedup(ctx context.Context, filter cache.DedupFilter, in []InChan, opts ...DedupOption) (*Dedup, *Monitor) {
+	ctx, canc := context.WithCancel(ctx)
+
+	d := &Dedup{
+		filter: filter,
+		keyfn:  func(b []byte) string { return string(b) },
+
+		outgoing: make(chan [][]byte, 16),
+		ctx:      ctx,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	d.donewg.Add(1)
+	monitor := NewMonitor(&d.donewg, canc)
+
+	d.monitor = monitor
+
+	go func() {
+		defer d.donewg.Done()
+		var inputwg sync.WaitGroup
+		inputwg.Add(len(in))
+		for _, ch := range in {
+			go func(ch InChan) {
+				defer inputwg.Done()
+				d.dedup(ch)
+			}(ch)
+		}
+		inputwg.Wait()
+		close(d.outgoing)
+		// success is decided across inputs, not by whichever finishes last
+		d.monitor.SetSuccess(d.ctx.Err() == nil)
+	}()
+
+	return d, monitor
+}
+
+func (d *Dedup) dedup(in <-chan [][]byte) {
+	passed := 0
+	dropped := 0
+	start := time.Now()
+
+	for chunk := range in {
+		select {
+		case <-d.ctx.Done():
+			d.cancelrep.submit(d.monitor, "dedupstream", d.ctx)
+			continue
+		default:
+			bufs := make([][]byte, 0, len(chunk))
+			for _, bytes := range chunk {
+				if d.filter.TestAndAdd(d.keyfn(bytes)) {
+					dropped++
+					continue
+				}
+				passed++
+				bufs = append(bufs, bytes)
+			}
+			// empty chunks are still sent, a Checkpoint counts them
+			d.outgoing <- bufs
+		}
+	}
+	d.monitor.SubmitStat(fmt.Sprintf("\nsuccessful dedup of %v messages; %v duplicates dropped; finished in %s",
+		passed, dropped, time.Since(start)))
+}
+
+func (d *Dedup) GetStream() <-chan [][]byte {
+	return d.outgoing
+}
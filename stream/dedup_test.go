@@ -0,0 +1,121 @@
+package stream
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/dustinevan/go-utils/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedup(t *testing.T) {
+	firstByte := func(b []byte) string { return string(b[:1]) }
+	tests := []struct {
+		name     string
+		in       []InChan
+		opts     []DedupOption
+		canceled bool
+		// want is the sorted keys of the records passed
+		want []string
+	}{
+		{
+			name: "one input",
+			in:   []InChan{send([]string{"a", "b", "a"}, []string{"b", "c"})},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "inputs share duplicates",
+			in: []InChan{
+				send([]string{"a", "b"}, []string{"a"}),
+				send([]string{"b", "c"}, []string{"c", "d"}),
+				send([]string{"d", "a", "e"}),
+			},
+			want: []string{"a", "b", "c", "d", "e"},
+		},
+		{
+			name: "key",
+			in:   []InChan{send([]string{"a1", "b1"}), send([]string{"a2", "b2", "c1"})},
+			opts: []DedupOption{DedupKey(firstByte)},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name:     "canceled",
+			in:       []InChan{chunks("r", 100), chunks("s", 100)},
+			canceled: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := cache.NewRotatingBloom(time.Hour, 2, 1000, 0.0001)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, canc := context.WithCancel(context.Background())
+			defer canc()
+			if tt.canceled {
+				canc()
+			}
+			d, mon := NewDedup(ctx, filter, tt.in, tt.opts...)
+
+			keyfn := firstByte
+			if tt.opts == nil {
+				keyfn = func(b []byte) string { return string(b) }
+			}
+			done := make(chan []string)
+			go func() {
+				var keys []string
+				for _, rec := range collect(d.GetStream()) {
+					keys = append(keys, keyfn([]byte(rec)))
+				}
+				sort.Strings(keys)
+				done <- keys
+			}()
+			select {
+			case keys := <-done:
+				assert.Equal(t, tt.want, keys, "wrong records passed")
+			case <-time.After(2 * time.Second):
+				t.Fatal("output wasn't closed")
+			}
+
+			var canceled int
+			for _, err := range monitorErrs(mon) {
+				if IsCanceled(err) {
+					canceled++
+				}
+			}
+			assert.Equal(t, !tt.canceled, mon.GetSuccess(), "wrong success")
+			assert.Equal(t, tt.canceled, canceled == 1, "the cancel wasn't reported once")
+		})
+	}
+}
+
+func TestDedupWindow(t *testing.T) {
+	filter, err := cache.NewRotatingBloom(40*time.Millisecond, 2, 1000, 0.0001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := make(chan [][]byte)
+	d, _ := NewDedup(context.Background(), filter, []InChan{in})
+	out := d.GetStream()
+
+	passed := func(recs ...string) []string {
+		chunk := make([][]byte, len(recs))
+		for i, r := range recs {
+			chunk[i] = []byte(r)
+		}
+		in <- chunk
+		var got []string
+		for _, b := range <-out {
+			got = append(got, string(b))
+		}
+		return got
+	}
+	assert.Equal(t, []string{"a", "b"}, passed("a", "b", "a"), "duplicate passed")
+	assert.Nil(t, passed("a", "b"), "duplicate passed within the window")
+	// keys are forgotten once the window has passed
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, []string{"a"}, passed("a"), "key was remembered past the window")
+	close(in)
+}
@@ -0,0 +1,234 @@
+package stream
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type SortOrder int
+
+const (
+	// ByName sorts by full path
+	ByName SortOrder = iota
+	// ByMtime sorts oldest first, e.g. for rotated logs
+	ByMtime
+)
+
+type ExpandOption func(e *expander) *expander
+
+// Recursive walks into subdirectories, by default only the files directly
+// in a directory are used
+func Recursive() ExpandOption {
+	return func(e *expander) *expander {
+		e.recursive = true
+		return e
+	}
+}
+
+// Include keeps only the files matching one of patterns. Patterns use
+// filepath.Match syntax and are matched against the base name and the path
+// relative to the directory being walked.
+func Include(patterns ...string) ExpandOption {
+	return func(e *expander) *expander {
+		e.include = append(e.include, patterns...)
+		return e
+	}
+}
+
+// Exclude drops files matching one of patterns, a matching directory isn't
+// walked at all
+func Exclude(patterns ...string) ExpandOption {
+	return func(e *expander) *expander {
+		e.exclude = append(e.exclude, patterns...)
+		return e
+	}
+}
+
+// SortBy sets the order of the files of each glob or directory, the default
+// is ByName. Reading the files with more than one open, see MaxOpen, only
+// keeps this order for when they're opened, not for their records.
+func SortBy(o SortOrder) ExpandOption {
+	return func(e *expander) *expander {
+		e.order = o
+		return e
+	}
+}
+
+type expander struct {
+	recursive bool
+	include   []string
+	exclude   []string
+	order     SortOrder
+
+	seen  map[string]bool
+	files []expanded
+}
+
+type expanded struct {
+	path  string
+	mtime time.Time
+}
+
+// Expand turns paths, globs and directories into a list of files. The files
+// of each glob or directory are sorted, see SortBy, and each path's files
+// come in the order the paths are given, so explicit files are read in the
+// caller's order. Files named explicitly are kept whatever the filters, and
+// a glob that matches nothing is an error so typos don't go unnoticed.
+//
+// Symlinks to files are followed, symlinks to directories inside a walked
+// directory aren't, so a link cycle can't loop forever.
+func Expand(paths []string, opts ...ExpandOption) ([]string, error) {
+	e := &expander{seen: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	var out []string
+	for _, p := range paths {
+		e.files = nil
+		if !hasMeta(p) {
+			err := e.add(p, true)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, e.sorted()...)
+			continue
+		}
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %s", p)
+		}
+		for _, m := range matches {
+			err = e.add(m, false)
+			if err != nil {
+				return nil, err
+			}
+		}
+		out = append(out, e.sorted()...)
+	}
+	return out, nil
+}
+
+// sorted is the paths of the files found for one of Expand's paths
+func (e *expander) sorted() []string {
+	switch e.order {
+	case ByMtime:
+		sort.SliceStable(e.files, func(i, j int) bool {
+			if e.files[i].mtime.Equal(e.files[j].mtime) {
+				return e.files[i].path < e.files[j].path
+			}
+			return e.files[i].mtime.Before(e.files[j].mtime)
+		})
+	default:
+		sort.Slice(e.files, func(i, j int) bool {
+			return e.files[i].path < e.files[j].path
+		})
+	}
+
+	out := make([]string, len(e.files))
+	for i, f := range e.files {
+		out[i] = f.path
+	}
+	return out
+}
+
+func (e *expander) add(path string, explicit bool) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		if explicit || e.keep(filepath.Base(path), filepath.Base(path)) {
+			e.file(path, info)
+		}
+		return nil
+	}
+
+	// WalkDir doesn't follow a symlinked root without a trailing separator
+	root := path
+	if li, err := os.Lstat(path); err == nil && li.Mode()&fs.ModeSymlink != 0 {
+		root = path + string(filepath.Separator)
+	}
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, p)
+		if d.IsDir() {
+			if p == root {
+				return nil
+			}
+			if !e.recursive || e.excluded(d.Name(), rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !e.keep(d.Name(), rel) {
+			return nil
+		}
+		var info fs.FileInfo
+		if d.Type()&fs.ModeSymlink != 0 {
+			info, err = os.Stat(p)
+			if err != nil {
+				// a dangling link
+				return nil
+			}
+		} else {
+			info, err = d.Info()
+			if err != nil {
+				return err
+			}
+		}
+		if info.Mode().IsRegular() {
+			e.file(p, info)
+		}
+		return nil
+	})
+}
+
+func (e *expander) file(path string, info fs.FileInfo) {
+	path = filepath.Clean(path)
+	if e.seen[path] {
+		return
+	}
+	e.seen[path] = true
+	e.files = append(e.files, expanded{path: path, mtime: info.ModTime()})
+}
+
+func (e *expander) keep(name, rel string) bool {
+	if e.excluded(name, rel) {
+		return false
+	}
+	if len(e.include) == 0 {
+		return true
+	}
+	return matchAny(e.include, name, rel)
+}
+
+func (e *expander) excluded(name, rel string) bool {
+	return matchAny(e.exclude, name, rel)
+}
+
+func matchAny(patterns []string, name, rel string) bool {
+	for _, pat := range patterns {
+		if ok, _ := filepath.Match(pat, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pat, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func hasMeta(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
@@ -0,0 +1,73 @@
+package stream
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpand(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "sub", "deep"), 0755)
+	os.MkdirAll(filepath.Join(dir, ".git"), 0755)
+	for _, f := range []struct {
+		rel string
+		age time.Duration
+	}{
+		{"b.log", time.Hour},
+		{"a.log", 0},
+		{"c.txt", 0},
+		{"sub/d.log", 2 * time.Hour},
+		{"sub/deep/e.log", 0},
+		{".git/x.log", 0},
+	} {
+		p := filepath.Join(dir, f.rel)
+		os.WriteFile(p, []byte(f.rel+"\n"), 0644)
+		mtime := time.Now().Add(-f.age)
+		os.Chtimes(p, mtime, mtime)
+	}
+	os.MkdirAll(filepath.Join(dir, "links"), 0755)
+	os.Symlink(filepath.Join(dir, "a.log"), filepath.Join(dir, "links", "a.log"))
+	os.Symlink(filepath.Join(dir, "sub"), filepath.Join(dir, "links", "sub"))
+	os.Symlink(filepath.Join(dir, "gone.log"), filepath.Join(dir, "links", "gone.log"))
+
+	tests := []struct {
+		name string
+		args []string
+		opts []ExpandOption
+		want []string
+		err  bool
+	}{
+		{"directory", []string{"."}, nil, []string{"a.log", "b.log", "c.txt"}, false},
+		{"include", []string{"."}, []ExpandOption{Include("*.log")}, []string{"a.log", "b.log"}, false},
+		{"recursive", []string{"."}, []ExpandOption{Recursive(), Include("*.log"), Exclude(".git", "links")}, []string{"a.log", "b.log", "sub/d.log", "sub/deep/e.log"}, false},
+		{"oldest first", []string{"."}, []ExpandOption{Recursive(), Exclude(".git", "links", "sub/deep", "*.txt"), SortBy(ByMtime)}, []string{"sub/d.log", "b.log", "a.log"}, false},
+		{"glob", []string{"*.log", "a.log"}, nil, []string{"a.log", "b.log"}, false},
+		{"explicit file skips filters", []string{"c.txt"}, []ExpandOption{Include("*.log")}, []string{"c.txt"}, false},
+		{"explicit files keep their order", []string{"c.txt", "b.log", "sub/*.log"}, []ExpandOption{Include("*.log")}, []string{"c.txt", "b.log", "sub/d.log"}, false},
+		// a symlinked file is followed, a symlinked directory isn't walked and a dangling link is skipped
+		{"symlinks", []string{"links"}, []ExpandOption{Recursive()}, []string{"links/a.log"}, false},
+		{"symlinked directory argument", []string{"links/sub"}, nil, []string{"links/sub/d.log"}, false},
+		{"glob matching nothing", []string{"*.nope"}, nil, nil, true},
+		{"missing file", []string{"missing.log"}, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := make([]string, len(tt.args))
+			for i, a := range tt.args {
+				args[i] = filepath.Join(dir, a)
+			}
+			got, err := Expand(args, tt.opts...)
+			assert.Equal(t, tt.err, err != nil, "wrong error: %v", err)
+			var rel []string
+			for _, p := range got {
+				r, _ := filepath.Rel(dir, p)
+				rel = append(rel, filepath.ToSlash(r))
+			}
+			assert.Equal(t, tt.want, rel, "wrong files")
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Predicate keeps a record when it returns true
+type Predicate func(b []byte) bool
+
+type FilterOption func(f *Filter) *Filter
+
+// Filter drops the records pred rejects. Unlike a failing ConvertFn, a
+// dropped record isn't an error.
+type Filter struct {
+	pred Predicate
+
+	outgoing chan [][]byte
+
+	ctx       context.Context
+	cancelrep cancelReport
+	donewg    sync.WaitGroup
+	monitor   *Monitor
+}
+
+func NewFilter(ctx context.Context, pred Predicate, in []InChan, opts ...FilterOption) (*Filter, *Monitor) {
+	ctx, canc := context.WithCancel(ctx)
+
+	f := &Filter{
+		pred: pred,
+
+		outgoing: make(chan [][]byte, 16),
+		ctx:      ctx,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	f.donewg.Add(1)
+	monitor := NewMonitor(&f.donewg, canc)
+
+	f.monitor = monitor
+
+	go func() {
+		defer f.donewg.Done()
+		var inputwg sync.WaitGroup
+		inputwg.Add(len(in))
+		for _, ch := range in {
+			go func(ch InChan) {
+				defer inputwg.Done()
+				f.filter(ch)
+			}(ch)
+		}
+		inputwg.Wait()
+		close(f.outgoing)
+		// success is decided across inputs, not by whichever finishes last
+		f.monitor.SetSuccess(f.ctx.Err() == nil)
+	}()
+
+	return f, monitor
+}
+
+func (f *Filter) filter(in <-chan [][]byte) {
+	passed := 0
+	dropped := 0
+	start := time.Now()
+
+	for chunk := range in {
+		select {
+		case <-f.ctx.Done():
+			f.cancelrep.submit(f.monitor, "filterstream", f.ctx)
+			continue
+		default:
+			bufs := make([][]byte, 0, len(chunk))
+			for _, bytes := range chunk {
+				if !f.pred(bytes) {
+					dropped++
+					continue
+				}
+				passed++
+				bufs = append(bufs, bytes)
+			}
+			// empty chunks are still sent, a Checkpoint counts them
+			f.outgoing <- bufs
+		}
+	}
+	f.monitor.SubmitStat(fmt.Sprintf("\nsuccessful filter of %v messages; %v messages dropped; finished in %s",
+		passed, dropped, time.Since(start)))
+}
+
+func (f *Filter) GetStream() <-chan [][]byte {
+	return f.outgoing
+}
@@ -0,0 +1,89 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dustinevan/go-utils/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+// send puts each of chunks on a closed channel
+func send(chunks ...[]string) InChan {
+	ch := make(chan [][]byte, len(chunks))
+	for _, c := range chunks {
+		chunk := make([][]byte, len(c))
+		for i, rec := range c {
+			chunk[i] = []byte(rec)
+		}
+		ch <- chunk
+	}
+	close(ch)
+	return ch
+}
+
+func TestFilter(t *testing.T) {
+	keep := func(b []byte) bool { return bytes.HasPrefix(b, []byte("keep")) }
+	tests := []struct {
+		name   string
+		in     []InChan
+		want   []string
+		chunks int
+	}{
+		{"drops rejected", []InChan{send([]string{"keep 1\n", "drop\n"}, []string{"drop\n"}, []string{"keep 2\n"})}, []string{"keep 1\n", "keep 2\n"}, 3},
+		{"drops everything", []InChan{send([]string{"drop\n"})}, nil, 1},
+		{"no records", []InChan{send()}, nil, 0},
+		{"inputs", []InChan{send([]string{"keep 1\n"}), send([]string{"drop\n"})}, []string{"keep 1\n"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, mon := NewFilter(context.Background(), keep, tt.in)
+			// empty chunks are still sent, a Checkpoint counts them
+			var got []string
+			chunks := 0
+			for chunk := range f.GetStream() {
+				chunks++
+				for _, b := range chunk {
+					got = append(got, string(b))
+				}
+			}
+			assert.Equal(t, tt.want, got, "wrong records")
+			assert.Equal(t, tt.chunks, chunks, "chunks weren't passed on one for one")
+			assert.True(t, mon.GetSuccess(), "dropping records failed the filter")
+		})
+	}
+}
+
+// success is decided once every input is done, not by the first to finish
+func TestFilterInputsCanceled(t *testing.T) {
+	tests := []struct {
+		name  string
+		stage func(ctx context.Context, in []InChan) (<-chan [][]byte, *Monitor)
+	}{
+		{"filter", func(ctx context.Context, in []InChan) (<-chan [][]byte, *Monitor) {
+			f, mon := NewFilter(ctx, func([]byte) bool { return true }, in)
+			return f.GetStream(), mon
+		}},
+		{"dedup", func(ctx context.Context, in []InChan) (<-chan [][]byte, *Monitor) {
+			filter, _ := cache.NewRotatingBloom(time.Hour, 2, 1000, 0.0001)
+			d, mon := NewDedup(ctx, filter, in)
+			return d.GetStream(), mon
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, canc := context.WithCancel(context.Background())
+			open := make(chan [][]byte)
+			out, mon := tt.stage(ctx, []InChan{send([]string{"a\n"}), open})
+			assert.Equal(t, [][]byte{[]byte("a\n")}, <-out, "wrong records")
+			canc()
+			open <- [][]byte{[]byte("b\n")}
+			close(open)
+			for range out {
+			}
+			assert.False(t, mon.GetSuccess(), "canceled stage succeeded")
+		})
+	}
+}
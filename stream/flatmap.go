@@ -0,0 +1,115 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FlatMapFn turns one record into any number of records, none drops it
+type FlatMapFn func(b []byte) ([][]byte, error)
+
+type FlatMapOption func(f *FlatMap) *FlatMap
+
+// FlatMapPolicy sets what happens to records fn fails on, the default is Skip
+func FlatMapPolicy(p ErrorPolicy) FlatMapOption {
+	return func(f *FlatMap) *FlatMap {
+		f.policy = p
+		return f
+	}
+}
+
+type FlatMap struct {
+	fn FlatMapFn
+
+	policy ErrorPolicy
+	errs   *errorHandler
+
+	outgoing chan [][]byte
+
+	ctx       context.Context
+	cancelrep cancelReport
+	donewg    sync.WaitGroup
+	monitor   *Monitor
+}
+
+func NewFlatMap(ctx context.Context, fn FlatMapFn, in []InChan, opts ...FlatMapOption) (*FlatMap, *Monitor) {
+	ctx, canc := context.WithCancel(ctx)
+
+	f := &FlatMap{
+		fn: fn,
+
+		outgoing: make(chan [][]byte, 16),
+		ctx:      ctx,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	f.donewg.Add(1)
+	monitor := NewMonitor(&f.donewg, canc)
+
+	f.monitor = monitor
+	f.errs = newErrorHandler("flatmap", f.policy, func(err error) { monitor.SubmitErr(err) }, monitor.Fail)
+
+	go func() {
+		defer f.donewg.Done()
+		var inputwg sync.WaitGroup
+		inputwg.Add(len(in))
+		for _, ch := range in {
+			go func(ch InChan) {
+				defer inputwg.Done()
+				f.flatmap(ch)
+			}(ch)
+		}
+		inputwg.Wait()
+		close(f.outgoing)
+		// success is decided across inputs, not by whichever finishes last
+		if f.errs.ok() && f.ctx.Err() == nil {
+			f.monitor.SetSuccess(true)
+		}
+	}()
+
+	return f, monitor
+}
+
+func (f *FlatMap) flatmap(in <-chan [][]byte) {
+	consumed := 0
+	produced := 0
+	failed := 0
+	start := time.Now()
+
+	for chunk := range in {
+		select {
+		case <-f.ctx.Done():
+			f.cancelrep.submit(f.monitor, "flatmapstream", f.ctx)
+			continue
+		default:
+			bufs := make([][]byte, 0, len(chunk))
+			for _, bytes := range chunk {
+				out, err := f.fn(bytes)
+				if err != nil {
+					failed++
+					f.errs.failure(bytes, err)
+					continue
+				}
+				f.errs.success()
+				consumed++
+				produced += len(out)
+				bufs = append(bufs, out...)
+			}
+			if f.errs.stopped() {
+				continue
+			}
+			// empty chunks are still sent, a Checkpoint counts them
+			f.outgoing <- bufs
+		}
+	}
+	f.monitor.SubmitStat(fmt.Sprintf("\nsuccessful flatmap of %v messages into %v messages; %v messages failed; finished in %s",
+		consumed, produced, failed, time.Since(start)))
+}
+
+func (f *FlatMap) GetStream() <-chan [][]byte {
+	return f.outgoing
+}
@@ -0,0 +1,44 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// words splits a line into one record per word
+func words(b []byte) ([][]byte, error) {
+	if bytes.HasPrefix(b, []byte("bad")) {
+		return nil, errors.New("bad record")
+	}
+	var out [][]byte
+	for _, w := range bytes.Fields(b) {
+		out = append(out, append(w, '\n'))
+	}
+	return out, nil
+}
+
+func TestFlatMap(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []string
+		want []string
+		errs int
+	}{
+		{"splits", []string{"a b\n", "c\n"}, []string{"a\n", "b\n", "c\n"}, 0},
+		{"none drops", []string{"\n", "a\n"}, []string{"a\n"}, 0},
+		{"skips bad records", []string{"a\n", "bad\n", "b\n"}, []string{"a\n", "b\n"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, mon := NewFlatMap(context.Background(), words, []InChan{send(tt.in)})
+			assert.Equal(t, tt.want, collect(f.GetStream()), "wrong records")
+			errs := monitorErrs(mon)
+			assert.Len(t, errs, tt.errs, "wrong errors: %v", errs)
+			assert.Equal(t, tt.errs == 0, mon.GetSuccess(), "wrong success")
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// IDLE is returned by a RecordReader that has no record yet and is about to
+// block waiting for one, Read sends the records it has when it sees it.
+var IDLE = errors.New("no record available yet")
+
+type FollowOption func(f *Follower) *Follower
+
+// PollInterval is how often the file is checked when inotify isn't
+// available. With inotify it's how often the file is checked anyway, in
+// case an event is missed. The default is a second.
+func PollInterval(d time.Duration) FollowOption {
+	return func(f *Follower) *Follower {
+		f.interval = d
+		return f
+	}
+}
+
+func FollowDelim(d byte) FollowOption {
+	return func(f *Follower) *Follower {
+		f.delim = d
+		return f
+	}
+}
+
+// FromEnd skips what's in the file when following starts, like tail -f
+func FromEnd() FollowOption {
+	return func(f *Follower) *Follower {
+		f.fromEnd = true
+		return f
+	}
+}
+
+// Follower reads a file like tail -F. At the end of the file it waits for
+// more to be written instead of ending the stream. A trailing partial
+// record is held back until its delimiter arrives.
+//
+// When the path is replaced, e.g. by log rotation, the rest of the old file
+// is read and the new one is opened. When the file is truncated it's read
+// again from the start. Follower reads until its context, or the context of
+// the Read stage reading it, is canceled, or it's closed. Read then returns
+// a CanceledError.
+type Follower struct {
+	path     string
+	delim    byte
+	interval time.Duration
+	fromEnd  bool
+
+	file    *os.File
+	buf     *bufio.Reader
+	pos     int64
+	partial []byte
+	idled   bool
+
+	watch watcher
+	ctx   context.Context
+	canc  context.CancelFunc
+	// stop unhooks the Read stage's context, see withContext
+	stop func() bool
+
+	// mu is held by Read, Close waits for it after canceling ctx
+	mu     sync.Mutex
+	closed bool
+}
+
+// watcher wakes a Follower when the file might have changed
+type watcher interface {
+	// wait returns after an event, or once timeout has passed
+	wait(ctx context.Context, timeout time.Duration)
+	close()
+}
+
+func NewFollower(ctx context.Context, path string, opts ...FollowOption) (*Follower, error) {
+	ctx, canc := context.WithCancel(ctx)
+	f := &Follower{
+		path:     path,
+		delim:    '\n',
+		interval: time.Second,
+		ctx:      ctx,
+		canc:     canc,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	err := f.open()
+	if err != nil {
+		canc()
+		return nil, err
+	}
+	if f.fromEnd {
+		f.pos, err = f.file.Seek(0, io.SeekEnd)
+		if err != nil {
+			f.file.Close()
+			canc()
+			return nil, err
+		}
+		f.buf.Reset(f.file)
+	}
+
+	f.watch, err = newInotifyWatcher(path)
+	if err != nil {
+		f.watch = pollWatcher{}
+	}
+	return f, nil
+}
+
+func (f *Follower) open() error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	if f.file != nil {
+		f.file.Close()
+	}
+	f.file = file
+	f.pos = 0
+	if f.buf == nil {
+		f.buf = bufio.NewReader(file)
+	} else {
+		f.buf.Reset(file)
+	}
+	return nil
+}
+
+// withContext ties the Follower to the context of the Read stage reading it,
+// so a canceled stage doesn't leave it waiting for writes
+func (f *Follower) withContext(ctx context.Context) {
+	f.stop = context.AfterFunc(ctx, f.canc)
+}
+
+func (f *Follower) Read() ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for {
+		if f.ctx.Err() != nil {
+			return nil, canceled("follow "+f.path, f.ctx)
+		}
+		b, err := f.buf.ReadBytes(f.delim)
+		f.pos += int64(len(b))
+		if err == nil {
+			f.idled = false
+			if len(f.partial) > 0 {
+				b = append(f.partial, b...)
+				f.partial = nil
+			}
+			return b, nil
+		}
+		if err != io.EOF {
+			return nil, err
+		}
+		f.partial = append(f.partial, b...)
+
+		rec, again, err := f.changed()
+		if err != nil {
+			return nil, err
+		}
+		if rec != nil {
+			f.idled = false
+			return rec, nil
+		}
+		if again {
+			continue
+		}
+
+		if !f.idled {
+			f.idled = true
+			return nil, IDLE
+		}
+		f.watch.wait(f.ctx, f.interval)
+	}
+}
+
+// changed handles rotation and truncation once the current file is read
+// to its end, again means there's more to read now. A partial record left
+// at the end of a rotated file won't be finished, so it's returned as is.
+func (f *Follower) changed() (rec []byte, again bool, err error) {
+	cur, err := f.file.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+	st, err := os.Stat(f.path)
+	if err != nil {
+		// between a rename and the new file being created
+		return nil, false, nil
+	}
+
+	if !os.SameFile(cur, st) {
+		// the old file may have been written to after the last read
+		if cur.Size() > f.pos {
+			return nil, true, nil
+		}
+		err = f.open()
+		if err != nil {
+			return nil, false, nil
+		}
+		rec = f.partial
+		f.partial = nil
+		return rec, len(rec) == 0, nil
+	}
+
+	if cur.Size() < f.pos {
+		_, err = f.file.Seek(0, io.SeekStart)
+		if err != nil {
+			return nil, false, err
+		}
+		f.buf.Reset(f.file)
+		f.pos = 0
+		f.partial = nil
+		return nil, true, nil
+	}
+	return nil, false, nil
+}
+
+// Close stops a Read that's waiting for writes and closes the file
+func (f *Follower) Close() {
+	f.canc()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return
+	}
+	f.closed = true
+	if f.stop != nil {
+		f.stop()
+	}
+	f.watch.close()
+	f.file.Close()
+}
+
+func (f *Follower) Name() string {
+	return f.path
+}
+
+type pollWatcher struct{}
+
+func (pollWatcher) wait(ctx context.Context, timeout time.Duration) {
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+func (pollWatcher) close() {}
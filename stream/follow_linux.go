@@ -0,0 +1,50 @@
+package stream
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// inotifyWatcher watches the file's directory, so creates and renames from
+// log rotation wake it as well as writes. The fd is non blocking, so reads
+// go through the runtime poller and honor deadlines.
+type inotifyWatcher struct {
+	file *os.File
+	buf  []byte
+}
+
+func newInotifyWatcher(path string) (watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_NONBLOCK | syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	mask := uint32(syscall.IN_MODIFY | syscall.IN_CREATE | syscall.IN_MOVED_TO |
+		syscall.IN_MOVED_FROM | syscall.IN_DELETE | syscall.IN_ATTRIB)
+	_, err = syscall.InotifyAddWatch(fd, filepath.Dir(path), mask)
+	if err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return &inotifyWatcher{
+		file: os.NewFile(uintptr(fd), "inotify"),
+		buf:  make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1)),
+	}, nil
+}
+
+// wait doesn't decode the events, any of them is reason enough to look at
+// the file again
+func (w *inotifyWatcher) wait(ctx context.Context, timeout time.Duration) {
+	w.file.SetReadDeadline(time.Now().Add(timeout))
+	stop := context.AfterFunc(ctx, func() {
+		w.file.SetReadDeadline(time.Now())
+	})
+	defer stop()
+	w.file.Read(w.buf)
+}
+
+func (w *inotifyWatcher) close() {
+	w.file.Close()
+}
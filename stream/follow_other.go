@@ -0,0 +1,9 @@
+//go:build !linux
+
+package stream
+
+import "errors"
+
+func newInotifyWatcher(path string) (watcher, error) {
+	return nil, errors.New("inotify is only available on linux")
+}
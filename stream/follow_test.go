@@ -0,0 +1,136 @@
+package stream
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFollower(t *testing.T) {
+	tests := []struct {
+		name string
+		poll bool
+	}{
+		{"inotify", false},
+		{"poll", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTemp(t, "app.log", "a\nb")
+			ctx, canc := context.WithCancel(context.Background())
+			f, err := NewFollower(ctx, path, PollInterval(20*time.Millisecond))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			if tt.poll {
+				f.watch.close()
+				f.watch = pollWatcher{}
+			}
+			read, mon := NewRead(ctx, f)
+			records := make(chan string, 100)
+			go func() {
+				for chunk := range read.GetStream() {
+					for _, b := range chunk {
+						records <- string(b)
+					}
+				}
+			}()
+			expect := func(want string) {
+				t.Helper()
+				select {
+				case got := <-records:
+					assert.Equal(t, want, got, "wrong record")
+				case <-time.After(2 * time.Second):
+					t.Fatalf("timed out waiting for %q", want)
+				}
+			}
+
+			expect("a\n")
+			w, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+			w.Write([]byte("c\nd\n"))
+			expect("bc\n")
+			expect("d\n")
+
+			// rotation, the rest of the old file is read before the new one
+			w.Write([]byte("last"))
+			w.Close()
+			os.Rename(path, path+".1")
+			os.WriteFile(path, []byte("new\n"), 0644)
+			expect("last")
+			expect("new\n")
+
+			// truncation starts over
+			os.WriteFile(path, []byte("x\n"), 0644)
+			expect("x\n")
+
+			canc()
+			assert.False(t, mon.GetSuccess(), "canceled follow succeeded")
+		})
+	}
+}
+
+func TestFollowerFromEnd(t *testing.T) {
+	path := writeTemp(t, "app.log", "old\n")
+	ctx, canc := context.WithCancel(context.Background())
+	defer canc()
+	f, err := NewFollower(ctx, path, FromEnd(), PollInterval(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	read, _ := NewRead(ctx, f)
+
+	w, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	w.Write([]byte("new\n"))
+	w.Close()
+	select {
+	case chunk := <-read.GetStream():
+		assert.Equal(t, [][]byte{[]byte("new\n")}, chunk, "read from the start")
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the new record")
+	}
+}
+
+// a waiting Follower stops when its Read stage is canceled or when it's closed
+func TestFollowerStop(t *testing.T) {
+	tests := []struct {
+		name string
+		stop func(canc context.CancelFunc, f *Follower)
+	}{
+		{"stage canceled", func(canc context.CancelFunc, f *Follower) { canc() }},
+		{"closed", func(canc context.CancelFunc, f *Follower) { f.Close() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTemp(t, "app.log", "a\n")
+			f, err := NewFollower(context.Background(), path, PollInterval(time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			ctx, canc := context.WithCancel(context.Background())
+			defer canc()
+			read, _ := NewRead(ctx, f)
+			go func() {
+				for range read.GetStream() {
+				}
+			}()
+
+			time.Sleep(20 * time.Millisecond)
+			tt.stop(canc, f)
+			select {
+			case <-read.Drained():
+			case <-time.After(2 * time.Second):
+				t.Fatal("follower kept waiting after it was stopped")
+			}
+			_, err = f.Read()
+			assert.True(t, IsCanceled(err), "stopped follower didn't return a CanceledError: %v", err)
+			// a second close is a no-op
+			f.Close()
+		})
+	}
+}
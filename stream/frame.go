@@ -0,0 +1,190 @@
+package stream
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Framing is how the length of each record is encoded ahead of it
+type Framing int
+
+const (
+	// Fixed32 is a 4 byte unsigned length, big endian unless LittleEndian is set
+	Fixed32 Framing = iota
+	// Uvarint is a protobuf style varint length, as written by
+	// protodelim and writeDelimitedTo
+	Uvarint
+)
+
+type FrameOption func(f *FrameReader) *FrameReader
+
+func LittleEndian() FrameOption {
+	return func(f *FrameReader) *FrameReader {
+		f.order = binary.LittleEndian
+		return f
+	}
+}
+
+// MaxFrame bounds the size of a single record, a corrupt length
+// otherwise turns into a huge allocation. The default is 64MB.
+func MaxFrame(n int) FrameOption {
+	return func(f *FrameReader) *FrameReader {
+		f.max = uint64(n)
+		return f
+	}
+}
+
+// FrameReader reads length prefixed binary records. Records are emitted
+// without their length prefix.
+type FrameReader struct {
+	f       *FileReader
+	buf     *bufio.Reader
+	framing Framing
+	order   binary.ByteOrder
+	max     uint64
+	err     error
+}
+
+func NewFrameReader(f *FileReader, framing Framing, opts ...FrameOption) *FrameReader {
+	fr := &FrameReader{
+		f:       f,
+		framing: framing,
+		order:   binary.BigEndian,
+		max:     64 << 20,
+	}
+	for _, opt := range opts {
+		opt(fr)
+	}
+	fr.buf, fr.err = f.source()
+	return fr
+}
+
+func (fr *FrameReader) Read() ([]byte, error) {
+	if fr.err != nil {
+		return nil, fr.err
+	}
+	n, err := fr.length()
+	if err != nil {
+		// EOF is only clean on a frame boundary
+		if err != io.EOF {
+			fr.err = fmt.Errorf("%s: reading frame length: %s", fr.Name(), err)
+			return nil, fr.err
+		}
+		return nil, io.EOF
+	}
+	if n > fr.max {
+		fr.err = fmt.Errorf("%s: frame of %v bytes is over the %v byte limit", fr.Name(), n, fr.max)
+		return nil, fr.err
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(fr.buf, b)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		fr.err = fmt.Errorf("%s: reading frame: %s", fr.Name(), err)
+		return nil, fr.err
+	}
+	return b, nil
+}
+
+func (fr *FrameReader) length() (uint64, error) {
+	switch fr.framing {
+	case Uvarint:
+		return binary.ReadUvarint(fr.buf)
+	default:
+		var l [4]byte
+		_, err := io.ReadFull(fr.buf, l[:])
+		if err != nil {
+			return 0, err
+		}
+		return uint64(fr.order.Uint32(l[:])), nil
+	}
+}
+
+// Offset is where the last record read ends
+func (fr *FrameReader) Offset() int64 {
+	return fr.f.Offset()
+}
+
+func (fr *FrameReader) Close() {
+	fr.f.Close()
+}
+
+func (fr *FrameReader) Name() string {
+	return fr.f.Name()
+}
+
+type FrameWriterOption func(f *FrameWriter) *FrameWriter
+
+// WriteLittleEndian writes Fixed32 lengths little endian
+func WriteLittleEndian() FrameWriterOption {
+	return func(f *FrameWriter) *FrameWriter {
+		f.order = binary.LittleEndian
+		return f
+	}
+}
+
+func FrameRecords(framing Framing, opts ...FrameWriterOption) RecordWriterFn {
+	return func(w io.Writer) RecordWriter {
+		return NewFrameWriter(w, framing, opts...)
+	}
+}
+
+// FrameWriter prefixes each record with its length, the output reads back
+// with a FrameReader of the same Framing.
+type FrameWriter struct {
+	w       io.Writer
+	buf     *bufio.Writer
+	framing Framing
+	order   binary.ByteOrder
+}
+
+func NewFrameWriter(w io.Writer, framing Framing, opts ...FrameWriterOption) *FrameWriter {
+	fw := &FrameWriter{
+		w:       w,
+		buf:     bufio.NewWriter(w),
+		framing: framing,
+		order:   binary.BigEndian,
+	}
+	for _, opt := range opts {
+		opt(fw)
+	}
+	return fw
+}
+
+func (fw *FrameWriter) Write(record []byte) error {
+	var l [binary.MaxVarintLen64]byte
+	var n int
+	switch fw.framing {
+	case Uvarint:
+		n = binary.PutUvarint(l[:], uint64(len(record)))
+	default:
+		if uint64(len(record)) > math.MaxUint32 {
+			return fmt.Errorf("%s: record of %v bytes doesn't fit a 4 byte length", fw.Name(), len(record))
+		}
+		fw.order.PutUint32(l[:], uint32(len(record)))
+		n = 4
+	}
+	_, err := fw.buf.Write(l[:n])
+	if err != nil {
+		return err
+	}
+	_, err = fw.buf.Write(record)
+	return err
+}
+
+func (fw *FrameWriter) Flush() error {
+	return fw.buf.Flush()
+}
+
+func (fw *FrameWriter) Close() error {
+	return fw.buf.Flush()
+}
+
+func (fw *FrameWriter) Name() string {
+	return sinkName(fw.w)
+}
@@ -0,0 +1,43 @@
+package stream
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrameReader(t *testing.T) {
+	var uvarint []byte
+	for _, rec := range []string{"abc", "", "with\nnewline"} {
+		uvarint = binary.AppendUvarint(uvarint, uint64(len(rec)))
+		uvarint = append(uvarint, rec...)
+	}
+	little := append(binary.LittleEndian.AppendUint32(nil, 2), "hi"...)
+	// the second frame is cut short
+	truncated := append(binary.BigEndian.AppendUint32(nil, 2), "hi"...)
+	truncated = append(binary.BigEndian.AppendUint32(truncated, 5), "abc"...)
+
+	tests := []struct {
+		name    string
+		in      []byte
+		framing Framing
+		opts    []FrameOption
+		want    []string
+		err     bool
+	}{
+		{"uvarint", uvarint, Uvarint, nil, []string{"abc", "", "with\nnewline"}, false},
+		{"little endian", little, Fixed32, []FrameOption{LittleEndian()}, []string{"hi"}, false},
+		{"truncated frame", truncated, Fixed32, nil, []string{"hi"}, true},
+		{"over MaxFrame", binary.BigEndian.AppendUint32(nil, 1<<30), Fixed32, []FrameOption{MaxFrame(1024)}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewFrameReader(openFile(t, writeTemp(t, "in.bin", string(tt.in))), tt.framing, tt.opts...)
+			defer r.Close()
+			records, err := readAll(r)
+			assert.Equal(t, tt.want, records, "wrong records")
+			assert.Equal(t, tt.err, err != nil, "wrong error: %v", err)
+		})
+	}
+}
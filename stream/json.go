@@ -0,0 +1,255 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// JSONLReader reads one JSON value per line. Blank lines are skipped, a
+// line that isn't valid JSON is an error. Records keep their trailing '\n'.
+type JSONLReader struct {
+	f    *FileReader
+	buf  *bufio.Reader
+	line int
+	err  error
+}
+
+func NewJSONLReader(f *FileReader) *JSONLReader {
+	j := &JSONLReader{f: f}
+	j.buf, j.err = f.source()
+	return j
+}
+
+func (j *JSONLReader) Read() ([]byte, error) {
+	if j.err != nil {
+		return nil, j.err
+	}
+	for {
+		b, err := j.buf.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(b) == 0 && err == io.EOF {
+			return nil, io.EOF
+		}
+		j.line++
+		if len(bytes.TrimSpace(b)) == 0 {
+			continue
+		}
+		if !json.Valid(b) {
+			j.err = fmt.Errorf("%s: line %v is not valid json", j.Name(), j.line)
+			return nil, j.err
+		}
+		if b[len(b)-1] != '\n' {
+			b = append(b, '\n')
+		}
+		return b, nil
+	}
+}
+
+// Offset is where the last record read ends
+func (j *JSONLReader) Offset() int64 {
+	return j.f.Offset()
+}
+
+func (j *JSONLReader) Close() {
+	j.f.Close()
+}
+
+func (j *JSONLReader) Name() string {
+	return j.f.Name()
+}
+
+// JSONArrayReader streams the elements of a top level JSON array without
+// holding the whole document. Each element is emitted compacted, followed
+// by '\n', so the output is JSON Lines.
+type JSONArrayReader struct {
+	f       *FileReader
+	dec     *json.Decoder
+	started bool
+	err     error
+}
+
+func NewJSONArrayReader(f *FileReader) *JSONArrayReader {
+	j := &JSONArrayReader{f: f}
+	src, err := f.source()
+	if err != nil {
+		j.err = err
+		return j
+	}
+	j.dec = json.NewDecoder(src)
+	return j
+}
+
+func (j *JSONArrayReader) Read() ([]byte, error) {
+	if j.err != nil {
+		return nil, j.err
+	}
+	if !j.started {
+		tok, err := j.dec.Token()
+		if err != nil {
+			return nil, j.fail(err)
+		}
+		if d, ok := tok.(json.Delim); !ok || d != '[' {
+			return nil, j.fail(fmt.Errorf("expected a top level array, found %v", tok))
+		}
+		j.started = true
+	}
+
+	if !j.dec.More() {
+		tok, err := j.dec.Token()
+		if err != nil {
+			return nil, j.fail(err)
+		}
+		if d, ok := tok.(json.Delim); !ok || d != ']' {
+			return nil, j.fail(fmt.Errorf("expected the end of the array, found %v", tok))
+		}
+		j.err = io.EOF
+		return nil, io.EOF
+	}
+
+	var raw json.RawMessage
+	err := j.dec.Decode(&raw)
+	if err != nil {
+		return nil, j.fail(err)
+	}
+	var b bytes.Buffer
+	err = json.Compact(&b, raw)
+	if err != nil {
+		return nil, j.fail(err)
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+func (j *JSONArrayReader) fail(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	j.err = fmt.Errorf("%s: %s", j.Name(), err)
+	return j.err
+}
+
+// checkpointErr is why a JSONArrayReader can't be checkpointed: a resumed
+// read would start in the middle of the array
+func (j *JSONArrayReader) checkpointErr() error {
+	return fmt.Errorf("%s: a json array can't be resumed, use JSON Lines for checkpointed reads", j.Name())
+}
+
+func (j *JSONArrayReader) Close() {
+	j.f.Close()
+}
+
+func (j *JSONArrayReader) Name() string {
+	return j.f.Name()
+}
+
+func JSONLRecords() RecordWriterFn {
+	return func(w io.Writer) RecordWriter {
+		return NewJSONLWriter(w)
+	}
+}
+
+// JSONLWriter writes one JSON value per line. A record that isn't valid
+// JSON is an error and nothing is written for it.
+type JSONLWriter struct {
+	w   io.Writer
+	buf *bufio.Writer
+}
+
+func NewJSONLWriter(w io.Writer) *JSONLWriter {
+	return &JSONLWriter{
+		w:   w,
+		buf: bufio.NewWriter(w),
+	}
+}
+
+func (j *JSONLWriter) Write(record []byte) error {
+	record = trimNewline(record)
+	if !json.Valid(record) {
+		return fmt.Errorf("%s: record is not valid json", j.Name())
+	}
+	_, err := j.buf.Write(record)
+	if err != nil {
+		return err
+	}
+	return j.buf.WriteByte('\n')
+}
+
+func (j *JSONLWriter) Flush() error {
+	return j.buf.Flush()
+}
+
+func (j *JSONLWriter) Close() error {
+	return j.buf.Flush()
+}
+
+func (j *JSONLWriter) Name() string {
+	return sinkName(j.w)
+}
+
+func JSONArrayRecords() RecordWriterFn {
+	return func(w io.Writer) RecordWriter {
+		return NewJSONArrayWriter(w)
+	}
+}
+
+// JSONArrayWriter writes records as the elements of a single top level
+// array, one per line. Close writes the closing bracket, an empty stream
+// is written as [].
+type JSONArrayWriter struct {
+	w     io.Writer
+	buf   *bufio.Writer
+	count int
+}
+
+func NewJSONArrayWriter(w io.Writer) *JSONArrayWriter {
+	return &JSONArrayWriter{
+		w:   w,
+		buf: bufio.NewWriter(w),
+	}
+}
+
+func (j *JSONArrayWriter) Write(record []byte) error {
+	record = trimNewline(record)
+	if !json.Valid(record) {
+		return fmt.Errorf("%s: record is not valid json", j.Name())
+	}
+	sep := ",\n"
+	if j.count == 0 {
+		sep = "[\n"
+	}
+	_, err := j.buf.WriteString(sep)
+	if err != nil {
+		return err
+	}
+	_, err = j.buf.Write(record)
+	if err != nil {
+		return err
+	}
+	j.count++
+	return nil
+}
+
+func (j *JSONArrayWriter) Flush() error {
+	return j.buf.Flush()
+}
+
+func (j *JSONArrayWriter) Close() error {
+	end := "\n]\n"
+	if j.count == 0 {
+		end = "[]\n"
+	}
+	_, err := j.buf.WriteString(end)
+	if err != nil {
+		return err
+	}
+	return j.buf.Flush()
+}
+
+func (j *JSONArrayWriter) Name() string {
+	return sinkName(j.w)
+}
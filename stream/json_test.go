@@ -0,0 +1,39 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONReaders(t *testing.T) {
+	jsonl := func(f *FileReader) RecordReader { return NewJSONLReader(f) }
+	array := func(f *FileReader) RecordReader { return NewJSONArrayReader(f) }
+	tests := []struct {
+		name   string
+		reader func(f *FileReader) RecordReader
+		in     string
+		want   []string
+		err    bool
+	}{
+		{"lines", jsonl, "{\"a\":1}\n\n  \n[1,2]\n\"last\"", []string{"{\"a\":1}\n", "[1,2]\n", "\"last\"\n"}, false},
+		{"invalid line", jsonl, "{\"a\":1}\n{bad\n{\"b\":2}\n", []string{"{\"a\":1}\n"}, true},
+		{"array", array, " [ {\"a\": 1,\n \"b\": [1, 2]}, 2, \"s\", null ] ", []string{"{\"a\":1,\"b\":[1,2]}\n", "2\n", "\"s\"\n", "null\n"}, false},
+		{"not an array", array, "{\"a\": 1}", nil, true},
+		{"unterminated array", array, "[1, 2", []string{"1\n", "2\n"}, true},
+		{"invalid element", array, "[1, }", []string{"1\n"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.reader(openFile(t, writeTemp(t, "in.json", tt.in)))
+			defer r.Close()
+			records, err := readAll(r)
+			assert.Equal(t, tt.want, records, "wrong records")
+			assert.Equal(t, tt.err, err != nil, "wrong error: %v", err)
+			if tt.err {
+				_, err = r.Read()
+				assert.NotNil(t, err, "read went on after an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Decoder and Encoder move records between bytes and the types Map works on
+type Decoder[T any] func(b []byte) (T, error)
+
+type Encoder[T any] func(v T) ([]byte, error)
+
+func JSONDecoder[T any]() Decoder[T] {
+	return func(b []byte) (T, error) {
+		var v T
+		err := json.Unmarshal(b, &v)
+		return v, err
+	}
+}
+
+// JSONEncoder encodes one value per line, ready for JSONLRecords or raw output
+func JSONEncoder[T any]() Encoder[T] {
+	return func(v T) ([]byte, error) {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return append(b, '\n'), nil
+	}
+}
+
+// Map is a Convert over typed values, records are decoded before fn and
+// encoded after. It takes the same options as Convert, e.g. Workers.
+type Map[In, Out any] struct {
+	*Convert
+}
+
+func NewMap[In, Out any](ctx context.Context, dec Decoder[In], fn func(In) (Out, error), enc Encoder[Out], in []InChan, opts ...ConvertOption) (*Map[In, Out], *Monitor) {
+	convert := func(b []byte) ([]byte, error) {
+		v, err := dec(b)
+		if err != nil {
+			return nil, err
+		}
+		out, err := fn(v)
+		if err != nil {
+			return nil, err
+		}
+		return enc(out)
+	}
+	c, monitor := NewConvert(ctx, convert, in, opts...)
+	return &Map[In, Out]{c}, monitor
+}
@@ -0,0 +1,46 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type event struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type total struct {
+	Name  string `json:"name"`
+	Total int    `json:"total"`
+}
+
+func TestMap(t *testing.T) {
+	double := func(e event) (total, error) {
+		if e.Count < 0 {
+			return total{}, errors.New("negative count")
+		}
+		return total{e.Name, e.Count * 2}, nil
+	}
+	tests := []struct {
+		name string
+		in   []string
+		want []string
+		errs int
+	}{
+		{"maps", []string{`{"name":"a","count":2}`, `{"name":"c","count":3}`}, []string{"{\"name\":\"a\",\"total\":4}\n", "{\"name\":\"c\",\"total\":6}\n"}, 0},
+		{"decode error", []string{"not json\n", `{"name":"a","count":1}`}, []string{"{\"name\":\"a\",\"total\":2}\n"}, 1},
+		{"fn error", []string{`{"name":"b","count":-1}`}, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, mon := NewMap(context.Background(), JSONDecoder[event](), double, JSONEncoder[total](), []InChan{send(tt.in)}, Workers(2))
+			assert.Equal(t, tt.want, collect(m.GetStream()), "wrong records")
+			errs := monitorErrs(mon)
+			assert.Len(t, errs, tt.errs, "wrong errors: %v", errs)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package stream
 
 import (
 	"context"
+	"strings"
 	"sync"
 
 	"log"
@@ -16,16 +17,24 @@ var STAT_FULL = errors.New("max number of inflight stats messages reached")
 type Monitor struct {
 	success bool
 
-	stats        chan string
-	statinflight int
-	statlimit    int
+	stats       chan string
+	statlimit   int
+	statdropped int
 
-	errs        chan error
-	errinflight int
-	errlimit    int
+	errs       chan error
+	errlimit   int
+	errdropped int
 
 	mu sync.Mutex
 
+	// failure is why the routine stopped early, see Fail
+	failure  error
+	failed   chan struct{}
+	failonce sync.Once
+
+	// joined are the monitors combined by JoinMonitors
+	joined []*Monitor
+
 	routinewg   *sync.WaitGroup
 	routinecanc context.CancelFunc
 }
@@ -36,6 +45,7 @@ func NewMonitor(routinewg *sync.WaitGroup, routinecanc context.CancelFunc) *Moni
 		stats:     make(chan string, 16),
 		statlimit: 16,
 		errlimit:  16,
+		failed:    make(chan struct{}),
 
 		routinewg:   routinewg,
 		routinecanc: routinecanc,
@@ -50,32 +60,46 @@ func NewMonitor(routinewg *sync.WaitGroup, routinecanc context.CancelFunc) *Moni
 	return m
 }
 
+// JoinMonitors combines ms into one Monitor. Its errors and stats are those of
+// ms, it succeeds if all of ms succeed, its Failed is closed when any of ms
+// fails and its Err reports every one that did.
 func JoinMonitors(ms ...*Monitor) *Monitor {
+	return joinMonitors(true, ms...)
+}
+
+// joinMonitors with forward false leaves the errors and stats of ms to be read
+// from each of them, the joined monitor only tracks success and failure.
+func joinMonitors(forward bool, ms ...*Monitor) *Monitor {
 
 	var wg sync.WaitGroup
 	p := &Monitor{
-		errs:         make(chan error, 1024),
-		stats:        make(chan string, 1024),
-		statlimit:    1024,
-		errlimit:     1024,
+		errs:      make(chan error, 1024),
+		stats:     make(chan string, 1024),
+		statlimit: 1024,
+		errlimit:  1024,
+		failed:    make(chan struct{}),
+		joined:    ms,
 		routinewg: &wg,
 	}
 
 	for _, m := range ms {
+		if !forward {
+			break
+		}
 		p.routinewg.Add(1)
-		go func() {
+		go func(m *Monitor) {
 			defer p.routinewg.Done()
 			for e := range m.ReadErrors() {
 				p.SubmitErr(e)
 			}
-		}()
+		}(m)
 		p.routinewg.Add(1)
-		go func() {
+		go func(m *Monitor) {
 			defer p.routinewg.Done()
 			for s := range m.ReadStats() {
 				p.SubmitStat(s)
 			}
-		}()
+		}(m)
 	}
 
 	p.routinecanc = func() {
@@ -90,10 +114,22 @@ func JoinMonitors(ms ...*Monitor) *Monitor {
 	var internalwg sync.WaitGroup
 	for _, m := range ms {
 		internalwg.Add(1)
-		go func() {
+		go func(m *Monitor) {
 			defer internalwg.Done()
 			m.routinewg.Wait()
-		}()
+		}(m)
+	}
+
+	// the first of ms to fail fails the joined monitor
+	done := make(chan struct{})
+	for _, m := range ms {
+		go func(m *Monitor) {
+			select {
+			case <-m.Failed():
+				p.failonce.Do(func() { close(p.failed) })
+			case <-done:
+			}
+		}(m)
 	}
 
 	// when all the underlying monitors complete, check the combined
@@ -101,10 +137,12 @@ func JoinMonitors(ms ...*Monitor) *Monitor {
 	p.routinewg.Add(1)
 	go func() {
 		internalwg.Wait()
-		p.success = true
+		close(done)
+		success := true
 		for _, m := range ms {
-			p.success = p.success && m.success
+			success = success && m.GetSuccess()
 		}
+		p.SetSuccess(success)
 		p.routinewg.Done()
 	}()
 
@@ -117,28 +155,44 @@ func JoinMonitors(ms ...*Monitor) *Monitor {
 	return p
 }
 
-// write methods are used by the thing being monitored. non-blocking
+// write methods are used by the thing being monitored. non-blocking, a stat
+// is dropped and counted when statlimit stats are waiting to be read
 func (m *Monitor) SubmitStat(s string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if m.statlimit == m.statinflight {
+	select {
+	case m.stats <- s:
+		return nil
+	default:
+		m.statdropped++
 		return STAT_FULL
 	}
-	m.stats <- s
-	m.statinflight++
-	return nil
 }
 
-// write methods are used by the thing being monitored. non-blocking
+// write methods are used by the thing being monitored. non-blocking, an error
+// is dropped and counted when errlimit errors are waiting to be read
 func (m *Monitor) SubmitErr(e error) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if m.errlimit == m.errinflight {
+	select {
+	case m.errs <- e:
+		return nil
+	default:
+		m.errdropped++
 		return STAT_FULL
 	}
-	m.errs <- e
-	m.errinflight++
-	return nil
+}
+
+func (m *Monitor) DroppedStats() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.statdropped
+}
+
+func (m *Monitor) DroppedErrors() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.errdropped
 }
 
 // returns the stat channel
@@ -151,15 +205,75 @@ func (m *Monitor) ReadErrors() <-chan error {
 }
 
 func (m *Monitor) SetSuccess(bool bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.success = bool
 }
 
+// Fail records why the routine is stopping early and cancels it. Unlike
+// SubmitErr it's never dropped; only the first call is recorded.
+func (m *Monitor) Fail(err error) {
+	m.failonce.Do(func() {
+		m.mu.Lock()
+		m.failure = err
+		m.mu.Unlock()
+		close(m.failed)
+	})
+	m.routinecanc()
+}
+
+// Failed is closed when Fail is called
+func (m *Monitor) Failed() <-chan struct{} {
+	return m.failed
+}
+
+// Err is the error Fail was called with. For a joined monitor it's every error
+// the joined monitors failed with.
+func (m *Monitor) Err() error {
+	m.mu.Lock()
+	failure := m.failure
+	m.mu.Unlock()
+	if failure != nil || len(m.joined) == 0 {
+		return failure
+	}
+	var errs joinedErrors
+	for _, j := range m.joined {
+		if err := j.Err(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return errs
+}
+
+// joinedErrors is the Err of a joined monitor more than one of whose monitors failed
+type joinedErrors []error
+
+func (e joinedErrors) Error() string {
+	s := make([]string, len(e))
+	for i, err := range e {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "; ")
+}
+
+func (e joinedErrors) Unwrap() []error {
+	return e
+}
+
 func (m *Monitor) CancelRoutine() {
 	m.routinecanc()
 }
 
 func (m *Monitor) GetSuccess() bool {
 	m.routinewg.Wait()
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	return m.success
 }
 
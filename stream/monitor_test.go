@@ -0,0 +1,82 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testMonitor() (*Monitor, *sync.WaitGroup) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	return NewMonitor(&wg, func() {}), &wg
+}
+
+func TestMonitorDroppedErrors(t *testing.T) {
+	m, wg := testMonitor()
+	for i := 0; i < m.errlimit+3; i++ {
+		m.SubmitErr(errors.New("e"))
+	}
+	assert.Equal(t, STAT_FULL, m.SubmitErr(errors.New("e")), "a full monitor took an error")
+	assert.Equal(t, 4, m.DroppedErrors(), "dropped errors weren't counted")
+	wg.Done()
+	assert.Len(t, monitorErrs(m), m.errlimit, "wrong number of errors kept")
+}
+
+func TestJoinMonitors(t *testing.T) {
+	errA, errB := errors.New("a failed"), context.DeadlineExceeded
+	tests := []struct {
+		name    string
+		fails   []error // nil succeeds
+		success bool
+		err     string
+	}{
+		{"all succeed", []error{nil, nil}, true, ""},
+		{"some fail", []error{errA, errB, nil}, false, "a failed; context deadline exceeded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ms []*Monitor
+			var wgs []*sync.WaitGroup
+			for range tt.fails {
+				m, wg := testMonitor()
+				ms = append(ms, m)
+				wgs = append(wgs, wg)
+			}
+			joined := JoinMonitors(ms...)
+
+			ms[0].SubmitErr(errors.New("e"))
+			ms[0].SubmitStat("s")
+			for i, err := range tt.fails {
+				if err == nil {
+					ms[i].SetSuccess(true)
+					continue
+				}
+				ms[i].Fail(err)
+				// the first failure fails the joined monitor right away
+				<-joined.Failed()
+			}
+			for _, wg := range wgs {
+				wg.Done()
+			}
+
+			assert.Equal(t, tt.success, joined.GetSuccess(), "wrong joined success")
+			assert.Equal(t, "e", (<-joined.ReadErrors()).Error(), "errors weren't forwarded")
+			assert.Equal(t, "s", <-joined.ReadStats(), "stats weren't forwarded")
+			err := joined.Err()
+			if tt.err == "" {
+				assert.Nil(t, err, "joined monitor has an error when none failed")
+				return
+			}
+			assert.Equal(t, tt.err, err.Error(), "wrong joined error")
+			for _, f := range tt.fails {
+				if f != nil {
+					assert.True(t, errors.Is(err, f), "joined Err is missing %v", f)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+)
+
+// StageFn builds a stage over the previous stage's output, it's how stages
+// without a Pipeline method, like Map, are added.
+type StageFn func(ctx context.Context, in []InChan) (InChan, *Monitor)
+
+type stage struct {
+	name  string
+	build StageFn
+}
+
+// Pipeline wires readers, stages and a writer together, e.g.
+//
+//	res := stream.From(readers...).Convert(fn).Filter(p).To(os.Stdout).Run(ctx)
+//
+// A read or write error cancels every stage, as does canceling ctx or a
+// stage failing, e.g. because of its ErrorPolicy. Other errors from the stages in
+// between are per record skips, they're reported in the Result but don't
+// stop the pipeline.
+type Pipeline struct {
+	readers   []RecordReader
+	files     []string
+	fileopts  []ReadFilesOption
+	readopts  []ReadOption
+	stages    []stage
+	w         io.Writer
+	writeopts []WriteOption
+	cp        *Checkpoint
+	keep      int
+	onerr     func(stage string, err error)
+	onstat    func(stage string, stat string)
+}
+
+// DefaultKeep is how many errors and stats of each stage a Result keeps
+const DefaultKeep = 100
+
+func From(readers ...RecordReader) *Pipeline {
+	return &Pipeline{readers: readers}
+}
+
+// FromFiles reads paths with a ReadFiles stage, so only a bounded number of
+// them are open at once. See Expand for globs and directories.
+func FromFiles(paths []string, opts ...ReadFilesOption) *Pipeline {
+	return &Pipeline{files: paths, fileopts: opts}
+}
+
+// ReadOptions applies to the Read stage of every reader, or every file
+func (p *Pipeline) ReadOptions(opts ...ReadOption) *Pipeline {
+	p.readopts = append(p.readopts, opts...)
+	return p
+}
+
+func (p *Pipeline) Convert(fn ConvertFn, opts ...ConvertOption) *Pipeline {
+	return p.Then("convert", func(ctx context.Context, in []InChan) (InChan, *Monitor) {
+		c, mon := NewConvert(ctx, fn, in, opts...)
+		return c.GetStream(), mon
+	})
+}
+
+func (p *Pipeline) Filter(pred Predicate, opts ...FilterOption) *Pipeline {
+	return p.Then("filter", func(ctx context.Context, in []InChan) (InChan, *Monitor) {
+		f, mon := NewFilter(ctx, pred, in, opts...)
+		return f.GetStream(), mon
+	})
+}
+
+func (p *Pipeline) FlatMap(fn FlatMapFn, opts ...FlatMapOption) *Pipeline {
+	return p.Then("flatmap", func(ctx context.Context, in []InChan) (InChan, *Monitor) {
+		f, mon := NewFlatMap(ctx, fn, in, opts...)
+		return f.GetStream(), mon
+	})
+}
+
+func (p *Pipeline) Then(name string, fn StageFn) *Pipeline {
+	p.stages = append(p.stages, stage{name: name, build: fn})
+	return p
+}
+
+func (p *Pipeline) To(w io.Writer, opts ...WriteOption) *Pipeline {
+	p.w = w
+	p.writeopts = opts
+	return p
+}
+
+// Checkpoint commits the progress of the reads to cp as the writer confirms
+// it. Readers passed to From should be created with ResumeFrom(cp) to pick
+// up where a previous run stopped, FromFiles does that itself.
+func (p *Pipeline) Checkpoint(cp *Checkpoint) *Pipeline {
+	p.cp = cp
+	return p
+}
+
+// Keep sets how many errors and stats of each stage the Result keeps, the
+// rest are only counted. Use OnError and OnStat to see all of them.
+func (p *Pipeline) Keep(n int) *Pipeline {
+	p.keep = n
+	return p
+}
+
+// OnError is called with every error of every stage as the pipeline runs.
+// Calls are serialized, a slow fn slows down the stages.
+func (p *Pipeline) OnError(fn func(stage string, err error)) *Pipeline {
+	p.onerr = fn
+	return p
+}
+
+// OnStat is called with every stat of every stage as the pipeline runs.
+// Calls are serialized, a slow fn slows down the stages.
+func (p *Pipeline) OnStat(fn func(stage string, stat string)) *Pipeline {
+	p.onstat = fn
+	return p
+}
+
+// Log logs every error and stat of every stage as the pipeline runs, like
+// Monitor.Log does for a single stage
+func (p *Pipeline) Log() *Pipeline {
+	p.onerr = func(stage string, err error) { log.Println(err) }
+	p.onstat = func(stage string, stat string) { log.Println(stat) }
+	return p
+}
+
+type StageResult struct {
+	Name    string
+	Success bool
+	// Stats and Errs are the first ones the stage reported, see Keep.
+	// NumStats and NumErrs count all of them.
+	Stats    []string
+	Errs     []error
+	NumStats int
+	NumErrs  int
+	// Dropped counts errors the stage's Monitor had no room for
+	Dropped int
+	// Err is why the stage failed, see Monitor.Fail
+	Err error
+	// Fatal stages, the reads and the write, stop the pipeline on any error
+	Fatal bool
+}
+
+type Result struct {
+	Success  bool
+	Canceled bool
+	Stages   []StageResult
+}
+
+// Err is why the first failed stage failed, or else the first error of the
+// first stage that had one
+func (r *Result) Err() error {
+	for _, s := range r.Stages {
+		if s.Err != nil {
+			return fmt.Errorf("%s: %s", s.Name, s.Err)
+		}
+	}
+	for _, s := range r.Stages {
+		if len(s.Errs) > 0 {
+			return fmt.Errorf("%s: %s", s.Name, s.Errs[0])
+		}
+	}
+	if !r.Success {
+		return fmt.Errorf("pipeline failed")
+	}
+	return nil
+}
+
+// Failure is why the pipeline stopped short: a stage failing, e.g. because
+// its ErrorPolicy tripped, an error from a read or the write, or ctx being
+// canceled. Records a stage skipped under its ErrorPolicy aren't failures,
+// Err reports those too.
+func (r *Result) Failure() error {
+	for _, s := range r.Stages {
+		if s.Err != nil {
+			return fmt.Errorf("%s: %w", s.Name, s.Err)
+		}
+	}
+	var canceled error
+	for _, s := range r.Stages {
+		if !s.Fatal {
+			continue
+		}
+		for _, err := range s.Errs {
+			if !IsCanceled(err) {
+				return fmt.Errorf("%s: %w", s.Name, err)
+			}
+			if canceled == nil {
+				canceled = err
+			}
+		}
+	}
+	if canceled != nil {
+		return canceled
+	}
+	if r.Canceled {
+		return &CanceledError{Stage: "pipeline", Err: context.Canceled}
+	}
+	return nil
+}
+
+type running struct {
+	name  string
+	mon   *Monitor
+	fatal bool
+}
+
+// Run blocks until every stage is done. Readers passed to From are closed
+// once their stage is done reading them.
+func (p *Pipeline) Run(ctx context.Context) *Result {
+	if p.w == nil {
+		return &Result{Stages: []StageResult{{Name: "pipeline", Errs: []error{fmt.Errorf("no writer, call To before Run")}, NumErrs: 1}}}
+	}
+	if len(p.readers) == 0 && len(p.files) == 0 {
+		return &Result{Stages: []StageResult{{Name: "pipeline", Errs: []error{fmt.Errorf("no readers or files to read")}, NumErrs: 1}}}
+	}
+
+	parent := ctx
+	ctx, canc := context.WithCancel(ctx)
+	defer canc()
+
+	readopts, writeopts := p.readopts, p.writeopts
+	if p.cp != nil {
+		readopts = append(readopts[:len(readopts):len(readopts)], ReadCheckpoint(p.cp))
+		writeopts = append(writeopts[:len(writeopts):len(writeopts)], WriteCheckpoint(p.cp))
+	}
+
+	var stages []running
+	var streams []InChan
+	// readers are closed once their stage has stopped reading them
+	var closewg sync.WaitGroup
+	for _, r := range p.readers {
+		read, mon := NewRead(ctx, r, readopts...)
+		streams = append(streams, read.GetStream())
+		stages = append(stages, running{name: "read " + r.Name(), mon: mon, fatal: true})
+		closewg.Add(1)
+		go func(r RecordReader) {
+			defer closewg.Done()
+			<-read.Drained()
+			r.Close()
+		}(r)
+	}
+	if len(p.files) > 0 {
+		fileopts := append(p.fileopts[:len(p.fileopts):len(p.fileopts)], FileReadOptions(readopts...))
+		if p.cp != nil {
+			fileopts = append(fileopts, FileOptions(ResumeFrom(p.cp)))
+		}
+		files, mon := NewReadFiles(ctx, p.files, fileopts...)
+		streams = append(streams, files.GetStream())
+		stages = append(stages, running{name: "read files", mon: mon, fatal: true})
+	}
+	for _, s := range p.stages {
+		out, mon := s.build(ctx, streams)
+		streams = []InChan{out}
+		stages = append(stages, running{name: s.name, mon: mon})
+	}
+	_, wmon := NewWrite(ctx, p.w, streams, writeopts...)
+	stages = append(stages, running{name: "write", mon: wmon, fatal: true})
+
+	keep := p.keep
+	if keep == 0 {
+		keep = DefaultKeep
+	}
+	res := &Result{Stages: make([]StageResult, len(stages))}
+	var mu sync.Mutex
+	var once sync.Once
+	cancelAll := func() {
+		once.Do(func() {
+			mu.Lock()
+			res.Canceled = true
+			mu.Unlock()
+			canc()
+		})
+	}
+
+	var drainwg sync.WaitGroup
+	for i, s := range stages {
+		res.Stages[i].Name = s.name
+		res.Stages[i].Fatal = s.fatal
+		drainwg.Add(2)
+		go func(i int, s running) {
+			defer drainwg.Done()
+			for e := range s.mon.ReadErrors() {
+				mu.Lock()
+				res.Stages[i].NumErrs++
+				if len(res.Stages[i].Errs) < keep {
+					res.Stages[i].Errs = append(res.Stages[i].Errs, e)
+				}
+				if p.onerr != nil {
+					p.onerr(s.name, e)
+				}
+				mu.Unlock()
+				if s.fatal && !IsCanceled(e) {
+					cancelAll()
+				}
+			}
+		}(i, s)
+		go func(i int, s running) {
+			defer drainwg.Done()
+			for st := range s.mon.ReadStats() {
+				mu.Lock()
+				res.Stages[i].NumStats++
+				if len(res.Stages[i].Stats) < keep {
+					res.Stages[i].Stats = append(res.Stages[i].Stats, st)
+				}
+				if p.onstat != nil {
+					p.onstat(s.name, st)
+				}
+				mu.Unlock()
+			}
+		}(i, s)
+	}
+
+	mons := make([]*Monitor, len(stages))
+	for i, s := range stages {
+		mons[i] = s.mon
+	}
+	// the stages' errors and stats are drained above, the joined monitor
+	// only tracks their success and failure
+	joined := joinMonitors(false, mons...)
+	finished := make(chan struct{})
+	go func() {
+		select {
+		case <-joined.Failed():
+			cancelAll()
+		case <-finished:
+		}
+	}()
+
+	res.Success = joined.GetSuccess()
+	close(finished)
+	for i, s := range stages {
+		res.Stages[i].Success = s.mon.GetSuccess()
+		res.Stages[i].Err = s.mon.Err()
+		res.Stages[i].Dropped = s.mon.DroppedErrors()
+	}
+	drainwg.Wait()
+	mu.Lock()
+	if parent.Err() != nil {
+		res.Canceled = true
+	}
+	canceled := res.Canceled
+	mu.Unlock()
+	if !canceled {
+		// a canceled read may still be blocked in Read, its reader is
+		// closed when it returns
+		closewg.Wait()
+	}
+	return res
+}
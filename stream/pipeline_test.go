@@ -0,0 +1,207 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sliceReader reads records from a slice
+type sliceReader struct {
+	records []string
+	closed  int32
+}
+
+func newSliceReader(s ...string) *sliceReader {
+	return &sliceReader{records: s}
+}
+
+func (r *sliceReader) Read() ([]byte, error) {
+	if len(r.records) == 0 {
+		return nil, io.EOF
+	}
+	rec := r.records[0]
+	r.records = r.records[1:]
+	return []byte(rec), nil
+}
+
+func (r *sliceReader) Close() {
+	atomic.AddInt32(&r.closed, 1)
+}
+
+func (r *sliceReader) Name() string {
+	return "slice"
+}
+
+func TestPipeline(t *testing.T) {
+	tests := []struct {
+		name    string
+		readers [][]string
+		stages  func(p *Pipeline) *Pipeline
+		want    string
+		success bool
+		errs    []int
+		numerrs []int
+	}{
+		{
+			name:    "stages",
+			readers: [][]string{{"a\n", "b\n", "skip\n"}, {"c\n"}},
+			stages: func(p *Pipeline) *Pipeline {
+				return p.Convert(upper).Filter(func(b []byte) bool { return !bytes.HasPrefix(b, []byte("SKIP")) })
+			},
+			want:    "A\nB\nC\n",
+			success: true,
+			errs:    []int{0, 0, 0, 0, 0},
+			numerrs: []int{0, 0, 0, 0, 0},
+		},
+		{
+			name:    "skipped record",
+			readers: [][]string{{"a\n", "bad\n", "c\n"}},
+			stages:  func(p *Pipeline) *Pipeline { return p.Convert(rejectBad) },
+			want:    "a\nc\n",
+			errs:    []int{0, 1, 0},
+			numerrs: []int{0, 1, 0},
+		},
+		{
+			name:    "errors past Keep are counted",
+			readers: [][]string{{"bad 1\n", "a\n", "bad 2\n", "bad 3\n"}},
+			stages:  func(p *Pipeline) *Pipeline { return p.Convert(rejectBad).Keep(2) },
+			want:    "a\n",
+			errs:    []int{0, 2, 0},
+			numerrs: []int{0, 3, 0},
+		},
+		{
+			name:    "no readers",
+			stages:  func(p *Pipeline) *Pipeline { return p },
+			errs:    []int{1},
+			numerrs: []int{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var readers []RecordReader
+			for _, records := range tt.readers {
+				readers = append(readers, newSliceReader(records...))
+			}
+			var out bytes.Buffer
+			seen := map[string]int{}
+			res := tt.stages(From(readers...)).
+				OnError(func(stage string, err error) { seen[stage]++ }).
+				To(&out).
+				Run(context.Background())
+			assert.Equal(t, tt.success, res.Success, "wrong success")
+			assert.Equal(t, tt.success, res.Err() == nil, "wrong error: %v", res.Err())
+			if len(tt.readers) > 0 {
+				assert.Nil(t, res.Failure(), "skipped records failed the pipeline")
+			}
+			assert.False(t, res.Canceled, "pipeline was canceled")
+			if assert.Len(t, res.Stages, len(tt.errs), "wrong stages") {
+				for i, s := range res.Stages {
+					assert.Len(t, s.Errs, tt.errs[i], "stage %s kept the wrong errors", s.Name)
+					assert.Equal(t, tt.numerrs[i], s.NumErrs, "stage %s counted the wrong errors", s.Name)
+					if s.Name != "pipeline" {
+						assert.Equal(t, s.NumErrs, seen[s.Name], "OnError didn't see every error of %s", s.Name)
+					}
+				}
+			}
+			lines := strings.SplitAfter(out.String(), "\n")
+			sort.Strings(lines)
+			assert.Equal(t, tt.want, strings.Join(lines, ""), "wrong output")
+			for _, r := range readers {
+				assert.Equal(t, int32(1), atomic.LoadInt32(&r.(*sliceReader).closed), "reader wasn't closed once")
+			}
+		})
+	}
+}
+
+func TestPipelineNoWriter(t *testing.T) {
+	res := From(newSliceReader("a\n")).Run(context.Background())
+	assert.False(t, res.Success, "pipeline without a writer succeeded")
+	assert.NotNil(t, res.Err(), "pipeline without a writer has no error")
+}
+
+func TestPipelineCanceled(t *testing.T) {
+	ctx, canc := context.WithCancel(context.Background())
+	canc()
+	var out bytes.Buffer
+	r := newSliceReader(strings.Split(strings.Repeat("x\n ", 100000), " ")...)
+	res := From(r).To(&out).Run(ctx)
+
+	assert.False(t, res.Success, "canceled pipeline succeeded")
+	assert.True(t, res.Canceled, "canceled pipeline wasn't reported canceled")
+	assert.True(t, IsCanceled(res.Failure()), "cancel wasn't reported as a CanceledError")
+}
+
+func TestPipelineFail(t *testing.T) {
+	var out bytes.Buffer
+	r := newSliceReader(strings.Split(strings.Repeat("x ", 10000), " ")...)
+	res := From(r).
+		Convert(func(b []byte) ([]byte, error) { return nil, errors.New("bad record") }, ConvertPolicy(FailFast())).
+		To(&out).
+		Run(context.Background())
+
+	assert.False(t, res.Success, "pipeline with a failed stage succeeded")
+	assert.True(t, res.Canceled, "a failed stage didn't cancel the pipeline")
+	assert.True(t, IsPolicyError(res.Stages[1].Err), "the failed stage's error is missing")
+	assert.True(t, IsPolicyError(res.Failure()), "wrong failure")
+	for _, s := range []StageResult{res.Stages[0], res.Stages[2]} {
+		assert.False(t, s.Success, "stage %s succeeded after a cancel", s.Name)
+	}
+}
+
+// logSignal closes logged on the first write to it
+type logSignal struct {
+	once   sync.Once
+	logged chan struct{}
+}
+
+func (w *logSignal) Write(b []byte) (int, error) {
+	w.once.Do(func() { close(w.logged) })
+	return len(b), nil
+}
+
+// waitReader reads a bad record, then waits for wait before it ends
+type waitReader struct {
+	wait   <-chan struct{}
+	n      int
+	waited bool
+}
+
+func (r *waitReader) Read() ([]byte, error) {
+	r.n++
+	if r.n == 1 {
+		return []byte("bad\n"), nil
+	}
+	select {
+	case <-r.wait:
+		r.waited = true
+	case <-time.After(2 * time.Second):
+	}
+	return nil, io.EOF
+}
+
+func (r *waitReader) Close()       {}
+func (r *waitReader) Name() string { return "wait" }
+
+func TestPipelineLog(t *testing.T) {
+	w := &logSignal{logged: make(chan struct{})}
+	log.SetOutput(w)
+	defer log.SetOutput(os.Stderr)
+
+	r := &waitReader{wait: w.logged}
+	var out bytes.Buffer
+	res := From(r).ReadOptions(ChunkSize(1)).Convert(rejectBad).Log().To(&out).Run(context.Background())
+	assert.Nil(t, res.Failure(), "pipeline failed")
+	assert.True(t, r.waited, "the skipped record wasn't logged while the pipeline ran")
+}
@@ -0,0 +1,184 @@
+package stream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrorPolicy decides what a stage does with a record that fails. The zero
+// value is Skip.
+type ErrorPolicy struct {
+	failAfter  int
+	failRatio  float64
+	minRecords int
+	deadletter *lockedWriter
+}
+
+// Skip reports the error and drops the record, the stage keeps going but
+// isn't successful
+func Skip() ErrorPolicy {
+	return ErrorPolicy{}
+}
+
+// FailFast stops the stage on the first bad record
+func FailFast() ErrorPolicy {
+	return ErrorPolicy{failAfter: 1}
+}
+
+// FailAfter skips bad records until there have been n of them
+func FailAfter(n int) ErrorPolicy {
+	return ErrorPolicy{failAfter: n}
+}
+
+// FailAbove skips bad records until more than ratio of them are bad, e.g.
+// 0.05 for 5%. The ratio isn't checked before minRecords have been seen,
+// so one early bad record doesn't stop the stage.
+func FailAbove(ratio float64, minRecords int) ErrorPolicy {
+	return ErrorPolicy{failRatio: ratio, minRecords: minRecords}
+}
+
+// DeadLetter writes each bad record and its error to w as a JSON encoded
+// DeadLetterRecord, and the stage stays successful. If w fails the stage
+// stops, nothing is dropped. Stages sharing w should share the policy,
+// writes through it are serialized. w is the caller's to close once the
+// stages are done.
+func DeadLetter(w RecordWriter) ErrorPolicy {
+	return ErrorPolicy{deadletter: &lockedWriter{w: w}}
+}
+
+type lockedWriter struct {
+	mu sync.Mutex
+	w  RecordWriter
+}
+
+func (l *lockedWriter) Write(record []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(record)
+}
+
+// DeadLetterRecord is what DeadLetter writes. Record is base64 in JSON, so
+// any bytes survive.
+type DeadLetterRecord struct {
+	Stage  string `json:"stage"`
+	Error  string `json:"error"`
+	Record []byte `json:"record"`
+}
+
+// PolicyError is what a stage fails with when it stops because of its
+// ErrorPolicy, see Monitor.Fail
+type PolicyError struct {
+	Stage   string
+	Errors  int
+	Records int
+	Last    error
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("%s: stopping after %v errors in %v records, last error: %s",
+		e.Stage, e.Errors, e.Records, e.Last)
+}
+
+func (e *PolicyError) Unwrap() error {
+	return e.Last
+}
+
+func IsPolicyError(err error) bool {
+	var p *PolicyError
+	return errors.As(err, &p)
+}
+
+// errorHandler applies a policy for one stage, it's safe for the stage's
+// goroutines to share.
+type errorHandler struct {
+	stage  string
+	policy ErrorPolicy
+	submit func(error)
+	fail   func(error)
+
+	mu      sync.Mutex
+	records int
+	errors  int
+	skipped int
+	tripped bool
+}
+
+// submit reports skipped records, fail is called once if the policy stops
+// the stage and should cancel it
+func newErrorHandler(stage string, policy ErrorPolicy, submit, fail func(error)) *errorHandler {
+	return &errorHandler{
+		stage:  stage,
+		policy: policy,
+		submit: submit,
+		fail:   fail,
+	}
+}
+
+func (h *errorHandler) success() {
+	h.mu.Lock()
+	h.records++
+	h.mu.Unlock()
+}
+
+// failure handles a bad record
+func (h *errorHandler) failure(rec []byte, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records++
+	h.errors++
+	if h.tripped {
+		return
+	}
+
+	if h.policy.deadletter != nil {
+		derr := h.deadletter(rec, err)
+		if derr == nil {
+			return
+		}
+		h.skipped++
+		h.trip(fmt.Errorf("dead letter write failed: %s, for error: %s", derr, err))
+		return
+	}
+
+	h.skipped++
+	h.submit(fmt.Errorf("%s: encountered error: %s on record: %s, skipping", h.stage, err, string(rec)))
+	p := h.policy
+	if p.failAfter > 0 && h.errors >= p.failAfter {
+		h.trip(err)
+		return
+	}
+	if p.failRatio > 0 && h.records >= p.minRecords && float64(h.errors)/float64(h.records) > p.failRatio {
+		h.trip(err)
+	}
+}
+
+func (h *errorHandler) trip(err error) {
+	h.tripped = true
+	h.fail(&PolicyError{Stage: h.stage, Errors: h.errors, Records: h.records, Last: err})
+}
+
+func (h *errorHandler) deadletter(rec []byte, err error) error {
+	b, jerr := json.Marshal(DeadLetterRecord{Stage: h.stage, Error: err.Error(), Record: rec})
+	if jerr != nil {
+		return jerr
+	}
+	return h.policy.deadletter.Write(b)
+}
+
+// stopped is true once the policy has stopped the stage. The chunk that
+// stopped it isn't passed on, so a Checkpoint can't commit past it.
+func (h *errorHandler) stopped() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.tripped
+}
+
+// ok is true when every record was handled, none skipped, and the policy
+// didn't stop the stage
+func (h *errorHandler) ok() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.skipped == 0 && !h.tripped
+}
@@ -0,0 +1,124 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorPolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   ErrorPolicy
+		outcomes string // + for a good record, - for a bad one
+		skipped  int
+		fail     *PolicyError
+	}{
+		{"skip", Skip(), "---", 3, nil},
+		{"fail fast", FailFast(), "+-+", 1, &PolicyError{Errors: 1, Records: 2}},
+		{"under FailAfter", FailAfter(3), "-+-", 2, nil},
+		{"FailAfter", FailAfter(3), "-+--+", 3, &PolicyError{Errors: 3, Records: 4}},
+		// below minRecords the ratio isn't checked
+		{"FailAbove before minRecords", FailAbove(0.2, 10), "-++", 1, nil},
+		{"at FailAbove", FailAbove(0.2, 10), "++++++++--", 2, nil},
+		{"above FailAbove", FailAbove(0.2, 10), "+++++++---", 3, &PolicyError{Errors: 3, Records: 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var skipped int
+			var fail error
+			h := newErrorHandler("test", tt.policy, func(error) { skipped++ }, func(err error) { fail = err })
+			for _, o := range tt.outcomes {
+				if o == '+' {
+					h.success()
+					continue
+				}
+				h.failure([]byte("rec"), errors.New("bad"))
+			}
+			assert.Equal(t, tt.skipped, skipped, "wrong number of skips")
+			assert.Equal(t, tt.skipped == 0 && tt.fail == nil, h.ok(), "wrong ok")
+			if tt.fail == nil {
+				assert.Nil(t, fail, "policy stopped the stage")
+				return
+			}
+			var p *PolicyError
+			if assert.True(t, errors.As(fail, &p), "policy didn't stop the stage") {
+				assert.Equal(t, "test", p.Stage, "wrong stage")
+				assert.Equal(t, tt.fail.Errors, p.Errors, "wrong error count")
+				assert.Equal(t, tt.fail.Records, p.Records, "wrong record count")
+				assert.Equal(t, "bad", errors.Unwrap(fail).Error(), "policy error doesn't unwrap to the last error")
+			}
+		})
+	}
+}
+
+// rejectBad fails records starting with bad
+func rejectBad(b []byte) ([]byte, error) {
+	if bytes.HasPrefix(b, []byte("bad")) {
+		return nil, errors.New("bad record")
+	}
+	return b, nil
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) error { return errors.New("disk full") }
+func (failingWriter) Close() error       { return nil }
+func (failingWriter) Name() string       { return "failing" }
+
+func TestStagePolicies(t *testing.T) {
+	var dead bytes.Buffer
+	dl := NewJSONLWriter(&dead)
+	tests := []struct {
+		name    string
+		stage   func(in []InChan) *Monitor
+		success bool
+		fail    bool
+	}{
+		{"convert skip", func(in []InChan) *Monitor {
+			c, mon := NewConvert(context.Background(), rejectBad, in)
+			go collect(c.GetStream())
+			return mon
+		}, false, false},
+		{"convert fail fast", func(in []InChan) *Monitor {
+			c, mon := NewConvert(context.Background(), rejectBad, in, ConvertPolicy(FailFast()))
+			go collect(c.GetStream())
+			return mon
+		}, false, true},
+		{"flatmap fail fast", func(in []InChan) *Monitor {
+			f, mon := NewFlatMap(context.Background(), words, in, FlatMapPolicy(FailFast()))
+			go collect(f.GetStream())
+			return mon
+		}, false, true},
+		{"write fail fast", func(in []InChan) *Monitor {
+			_, mon := NewWrite(context.Background(), &bytes.Buffer{}, in, Records(JSONLRecords()), WritePolicy(FailFast()))
+			return mon
+		}, false, true},
+		{"dead letter", func(in []InChan) *Monitor {
+			c, mon := NewConvert(context.Background(), rejectBad, in, ConvertPolicy(DeadLetter(dl)))
+			go collect(c.GetStream())
+			return mon
+		}, true, false},
+		{"failed dead letter", func(in []InChan) *Monitor {
+			c, mon := NewConvert(context.Background(), rejectBad, in, ConvertPolicy(DeadLetter(failingWriter{})))
+			go collect(c.GetStream())
+			return mon
+		}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mon := tt.stage([]InChan{send([]string{"1\n", "bad\n", "2\n"}, []string{"3\n"})})
+			assert.Equal(t, tt.success, mon.GetSuccess(), "wrong success")
+			assert.Equal(t, tt.fail, IsPolicyError(mon.Err()), "wrong failure: %v", mon.Err())
+		})
+	}
+
+	assert.Nil(t, dl.Close(), "closing the dead letters failed")
+	var rec DeadLetterRecord
+	assert.Nil(t, json.Unmarshal(dead.Bytes(), &rec), "dead letter isn't a DeadLetterRecord")
+	assert.Equal(t, DeadLetterRecord{Stage: "converter", Error: "bad record", Record: []byte("bad\n")}, rec, "wrong dead letter")
+}
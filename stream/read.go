@@ -17,29 +17,50 @@ func ChunkSize(n int) ReadOption {
 	}
 }
 
+// ReadCheckpoint registers the read's progress with cp, see Checkpoint.
+// Offsets come from RecordReaders with an Offset method, like FileReader.
+// A JSONArrayReader can't be resumed, the read fails if it's checkpointed.
+func ReadCheckpoint(cp *Checkpoint) ReadOption {
+	return func(stream *Read) *Read {
+		stream.cp = cp
+		return stream
+	}
+}
+
 type Read struct {
-	r RecordReader
+	r  RecordReader
+	cp *Checkpoint
 
 	chunksize int
 	outgoing  chan [][]byte
 
+	// drained is closed once the stage has stopped calling r.Read, the
+	// stage itself may finish before that, see read
+	drained chan struct{}
+
 	ctx     context.Context
 	donewg  sync.WaitGroup
 	monitor *Monitor
 }
 
-func NewRead(r RecordReader, opts ...ReadOption) (*Read, *Monitor) {
-	ctx, canc := context.WithCancel(context.Background())
+func NewRead(ctx context.Context, r RecordReader, opts ...ReadOption) (*Read, *Monitor) {
+	ctx, canc := context.WithCancel(ctx)
 
 	read := &Read{
 		r:         r,
 		chunksize: 100,
 		outgoing:  make(chan [][]byte, 16),
+		drained:   make(chan struct{}),
 		ctx:       ctx,
 	}
 	for _, opt := range opts {
 		opt(read)
 	}
+	// readers that block waiting for records, like Follower, stop when the
+	// stage is canceled
+	if c, ok := r.(interface{ withContext(context.Context) }); ok {
+		c.withContext(ctx)
+	}
 
 	read.donewg.Add(1)
 	monitor := NewMonitor(&read.donewg, canc)
@@ -55,37 +76,132 @@ func NewRead(r RecordReader, opts ...ReadOption) (*Read, *Monitor) {
 	return read, monitor
 }
 
+// read relays chunks from readChunks, which does the actual reading. A
+// Read blocked on a slow RecordReader doesn't hold up cancellation, the
+// stage finishes and readChunks exits once the Read returns.
 func (r *Read) read() {
-	bcount := 0
-	mcount := 0
+	chunks := make(chan readChunk)
+	done := make(chan readDone, 1)
 	start := time.Now()
+	if c, ok := r.r.(interface{ checkpointErr() error }); ok && r.cp != nil {
+		close(r.drained)
+		err := c.checkpointErr()
+		r.monitor.SubmitErr(err)
+		r.monitor.Fail(err)
+		return
+	}
+	go r.readChunks(chunks, done)
+
 	for {
 		select {
 		case <-r.ctx.Done():
-			r.monitor.SubmitErr(fmt.Errorf("readstream: canceled"))
+			r.monitor.SubmitErr(canceled("readstream", r.ctx))
 			return
-		default:
-			chunk := make([][]byte, r.chunksize)
-			for i := 0; i < r.chunksize; i++ {
-				bytes, err := r.r.Read()
-				if err != nil && err != io.EOF {
-					r.monitor.SubmitErr(err)
+		case rc, ok := <-chunks:
+			if !ok {
+				// readChunks only closes without a result when canceled
+				var d readDone
+				select {
+				case d = <-done:
+				case <-r.ctx.Done():
+					r.monitor.SubmitErr(canceled("readstream", r.ctx))
+					return
+				}
+				if d.err != nil {
+					r.monitor.SubmitErr(d.err)
 					r.monitor.SubmitStat(fmt.Sprintf("unsuccessful read of: %s read %v bytes %v message in %s",
-						r.r.Name(), bcount, mcount, time.Since(start)))
+						r.r.Name(), d.bcount, d.mcount, time.Since(start)))
 					return
 				}
-				if err == io.EOF {
-					r.outgoing <- chunk[:i]
-					r.monitor.SetSuccess(true)
-					r.monitor.SubmitStat(fmt.Sprintf("successful read of: %s read %v bytes %v message in %s",
-						r.r.Name(), bcount, mcount, time.Since(start)))
+				r.monitor.SetSuccess(true)
+				r.monitor.SubmitStat(fmt.Sprintf("successful read of: %s read %v bytes %v message in %s",
+					r.r.Name(), d.bcount, d.mcount, time.Since(start)))
+				return
+			}
+			if r.cp != nil {
+				err := r.cp.send(r.ctx, r.r.Name(), rc.pos)
+				if err != nil {
+					r.monitor.SubmitErr(canceled("readstream", r.ctx))
 					return
 				}
-				bcount += len(bytes)
-				mcount++
-				chunk[i] = bytes
 			}
-			r.outgoing <- chunk
+			select {
+			case r.outgoing <- rc.chunk:
+			case <-r.ctx.Done():
+				r.monitor.SubmitErr(canceled("readstream", r.ctx))
+				return
+			}
+		}
+	}
+}
+
+// readChunk carries where the input is after chunk
+type readChunk struct {
+	chunk [][]byte
+	pos   Progress
+}
+
+type readDone struct {
+	err    error
+	bcount int
+	mcount int
+}
+
+// readChunks doesn't touch the monitor, it may still be running after the
+// stage is done
+func (r *Read) readChunks(chunks chan<- readChunk, done chan<- readDone) {
+	defer close(r.drained)
+	defer close(chunks)
+	bcount := 0
+	mcount := 0
+	var base int64
+	if r.cp != nil {
+		p, _ := r.cp.Progress(r.r.Name())
+		base = p.Records
+	}
+	pos := func(eof bool) Progress {
+		p := Progress{Records: base + int64(mcount), Done: eof}
+		if o, ok := r.r.(interface{ Offset() int64 }); ok {
+			p.Offset = o.Offset()
+		}
+		return p
+	}
+	for {
+		if r.ctx.Err() != nil {
+			return
+		}
+		chunk := make([][]byte, r.chunksize)
+		i := 0
+		for i < r.chunksize {
+			bytes, err := r.r.Read()
+			if err == IDLE {
+				// the reader is about to block, send what there is
+				if i > 0 {
+					break
+				}
+				continue
+			}
+			if err != nil && err != io.EOF {
+				done <- readDone{err: err, bcount: bcount, mcount: mcount}
+				return
+			}
+			if err == io.EOF {
+				select {
+				case chunks <- readChunk{chunk: chunk[:i], pos: pos(true)}:
+					done <- readDone{bcount: bcount, mcount: mcount}
+				case <-r.ctx.Done():
+				}
+				return
+			}
+			bcount += len(bytes)
+			mcount++
+			chunk[i] = bytes
+			i++
+		}
+		select {
+		case chunks <- readChunk{chunk: chunk[:i], pos: pos(false)}:
+		case <-r.ctx.Done():
+			return
 		}
 	}
 }
@@ -93,3 +209,9 @@ func (r *Read) read() {
 func (r *Read) GetStream() <-chan [][]byte {
 	return r.outgoing
 }
+
+// Drained is closed once the stage will make no more calls to its
+// RecordReader's Read, after which it's safe to Close the reader
+func (r *Read) Drained() <-chan struct{} {
+	return r.drained
+}
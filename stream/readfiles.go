@@ -0,0 +1,158 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+type ReadFilesOption func(r *ReadFiles) *ReadFiles
+
+// MaxOpen bounds how many files are open, and read, at once. The default
+// is 16. With 1 the files are read one after another in the order given,
+// with more their records interleave, so the order given, e.g. by SortBy,
+// is only the order they're opened in.
+func MaxOpen(n int) ReadFilesOption {
+	return func(r *ReadFiles) *ReadFiles {
+		if n > 0 {
+			r.maxopen = n
+		}
+		return r
+	}
+}
+
+// FileOptions are applied to the FileReader of every file
+func FileOptions(opts ...FileReaderOption) ReadFilesOption {
+	return func(r *ReadFiles) *ReadFiles {
+		r.fileopts = append(r.fileopts, opts...)
+		return r
+	}
+}
+
+// FileReadOptions are applied to the Read of every file
+func FileReadOptions(opts ...ReadOption) ReadFilesOption {
+	return func(r *ReadFiles) *ReadFiles {
+		r.readopts = append(r.readopts, opts...)
+		return r
+	}
+}
+
+// ReadFiles reads many files into one stream. Files are opened only when
+// they're about to be read and closed once they're done, so no more than
+// maxopen are open at once however many there are.
+type ReadFiles struct {
+	paths    []string
+	maxopen  int
+	fileopts []FileReaderOption
+	readopts []ReadOption
+
+	failed int64
+
+	outgoing chan [][]byte
+
+	ctx     context.Context
+	donewg  sync.WaitGroup
+	monitor *Monitor
+}
+
+func NewReadFiles(ctx context.Context, paths []string, opts ...ReadFilesOption) (*ReadFiles, *Monitor) {
+	ctx, canc := context.WithCancel(ctx)
+
+	r := &ReadFiles{
+		paths:    paths,
+		maxopen:  16,
+		outgoing: make(chan [][]byte, 16),
+		ctx:      ctx,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	r.donewg.Add(1)
+	monitor := NewMonitor(&r.donewg, canc)
+
+	r.monitor = monitor
+
+	go func() {
+		defer r.donewg.Done()
+		r.run()
+	}()
+
+	return r, monitor
+}
+
+func (r *ReadFiles) run() {
+	next := make(chan string)
+	go func() {
+		defer close(next)
+		for _, p := range r.paths {
+			select {
+			case next <- p:
+			case <-r.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(r.maxopen)
+	for i := 0; i < r.maxopen; i++ {
+		go func() {
+			defer wg.Done()
+			for p := range next {
+				r.readFile(p)
+			}
+		}()
+	}
+	wg.Wait()
+	close(r.outgoing)
+
+	if r.ctx.Err() != nil {
+		r.monitor.SubmitErr(canceled("readfilesstream", r.ctx))
+		return
+	}
+	if atomic.LoadInt64(&r.failed) == 0 {
+		r.monitor.SetSuccess(true)
+	}
+}
+
+func (r *ReadFiles) readFile(path string) {
+	if r.ctx.Err() != nil {
+		return
+	}
+	option, err := FileToRead(path)
+	if err != nil {
+		atomic.AddInt64(&r.failed, 1)
+		r.monitor.SubmitErr(fmt.Errorf("readfilesstream: %s", err))
+		return
+	}
+	f := NewFileReader(nil, append([]FileReaderOption{option}, r.fileopts...)...)
+	read, mon := NewRead(r.ctx, f, r.readopts...)
+	// a canceled read finishes before its last Read of the file returns
+	defer func() {
+		<-read.Drained()
+		f.Close()
+	}()
+	for chunk := range read.GetStream() {
+		select {
+		case r.outgoing <- chunk:
+		case <-r.ctx.Done():
+		}
+	}
+	if !mon.GetSuccess() {
+		atomic.AddInt64(&r.failed, 1)
+	}
+	for s := range mon.ReadStats() {
+		r.monitor.SubmitStat(s)
+	}
+	for e := range mon.ReadErrors() {
+		if !IsCanceled(e) {
+			r.monitor.SubmitErr(e)
+		}
+	}
+}
+
+func (r *ReadFiles) GetStream() <-chan [][]byte {
+	return r.outgoing
+}
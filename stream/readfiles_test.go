@@ -0,0 +1,97 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// openCounter is a FileReaderOption tracking how many files are open at once
+type openCounter struct {
+	mu   sync.Mutex
+	open int
+	max  int
+}
+
+func (c *openCounter) option() FileReaderOption {
+	return func(f *FileReader) *FileReader {
+		c.mu.Lock()
+		c.open++
+		if c.open > c.max {
+			c.max = c.open
+		}
+		c.mu.Unlock()
+		f.postread = append(f.postread, func() {
+			c.mu.Lock()
+			c.open--
+			c.mu.Unlock()
+		})
+		return f
+	}
+}
+
+func TestReadFiles(t *testing.T) {
+	var paths, want []string
+	for i := 0; i < 200; i++ {
+		paths = append(paths, writeTemp(t, fmt.Sprintf("f%03d", i), fmt.Sprintf("%03d\n", i)))
+		want = append(want, fmt.Sprintf("%03d\n", i))
+	}
+	missing := append([]string{filepath.Join(t.TempDir(), "missing")}, paths[:3]...)
+
+	tests := []struct {
+		name    string
+		paths   []string
+		maxopen int
+		want    []string
+		ordered bool
+		success bool
+	}{
+		{"one open", paths, 1, want, true, true},
+		{"four open", paths, 4, want, false, true},
+		// a missing file stops the read, what's written before that varies
+		{"missing file", missing, 4, nil, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &openCounter{}
+			var out bytes.Buffer
+			res := FromFiles(tt.paths, MaxOpen(tt.maxopen), FileOptions(c.option())).To(&out).Run(context.Background())
+			assert.Equal(t, tt.success, res.Success, "wrong success: %v", res.Err())
+			assert.True(t, c.max <= tt.maxopen, "%v files were open at once", c.max)
+			assert.Equal(t, 0, c.open, "files were left open")
+			if tt.ordered {
+				assert.Equal(t, strings.Join(tt.want, ""), out.String(), "files weren't read in order")
+			}
+			if tt.want != nil {
+				got := strings.SplitAfter(out.String(), "\n")
+				sort.Strings(got)
+				assert.Equal(t, strings.Join(tt.want, ""), strings.Join(got, ""), "wrong output")
+			}
+		})
+	}
+}
+
+func TestReadFilesCanceled(t *testing.T) {
+	var paths []string
+	for i := 0; i < 50; i++ {
+		paths = append(paths, writeTemp(t, fmt.Sprintf("f%02d", i), strings.Repeat("record\n", 10000)))
+	}
+	c := &openCounter{}
+	ctx, canc := context.WithCancel(context.Background())
+	read, mon := NewReadFiles(ctx, paths, MaxOpen(4), FileOptions(c.option()))
+	<-read.GetStream()
+	canc()
+	for range read.GetStream() {
+	}
+	assert.False(t, mon.GetSuccess(), "canceled read succeeded")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	assert.Equal(t, 0, c.open, "canceled read left files open")
+}
@@ -1,11 +1,14 @@
 package stream
 
 import (
-	"os"
 	"bufio"
-	"sync"
+	"bytes"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
 type RecordReader interface {
@@ -16,6 +19,14 @@ type RecordReader interface {
 
 type FileReaderOption func(f *FileReader) *FileReader
 
+// ResumeFrom starts reading where cp last committed for this file
+func ResumeFrom(cp *Checkpoint) FileReaderOption {
+	return func(r *FileReader) *FileReader {
+		r.resume = cp
+		return r
+	}
+}
+
 func Delim(d byte) FileReaderOption {
 	return func(r *FileReader) *FileReader {
 		r.delim = d
@@ -41,21 +52,24 @@ func FileToRead(absfilename string) (FileReaderOption, error) {
 	}, nil
 }
 
-//TODO: Gzip, Lzop Options
-
 type FileReader struct {
 	buf   *bufio.Reader
 	file  *os.File
 	delim byte
 
-	decompress func(string) (*os.File, error)
-	filename   string
+	// decompress wraps the file in a streaming decompressor. autodetect
+	// picks one from the file's magic bytes or extension.
+	decompress Decompressor
+	autodetect bool
+	err        error
 
-	postread []func()
+	// count sits under buf, the offset of the next record is base plus what
+	// count has seen less what buf holds. base is where a resumed read started.
+	count  *countingReader
+	base   int64
+	resume *Checkpoint
 
-	wg    sync.WaitGroup
-	ready bool
-	empty bool
+	postread []func()
 }
 
 func NewFileReader(file *os.File, opts ...FileReaderOption) *FileReader {
@@ -67,36 +81,107 @@ func NewFileReader(file *os.File, opts ...FileReaderOption) *FileReader {
 		opt(f)
 	}
 
+	var src io.Reader = f.file
+	if f.autodetect && f.decompress == nil {
+		br := bufio.NewReader(f.file)
+		f.decompress = detect(br, f.file.Name())
+		src = br
+	}
+
 	if f.decompress == nil {
-		f.buf = bufio.NewReader(file)
+		f.count = &countingReader{r: src}
+		f.buf = bufio.NewReader(f.count)
+		f.seek()
 		return f
 	}
 
-	f.wg.Add(1)
-	go func() {
-		defer f.wg.Done()
-		df, err := f.decompress(f.filename)
-		if err != nil {
-			f.empty = true
-			f.ready = true
-			log.Printf("decompression failed %s")
+	dr, err := f.decompress(src)
+	if err != nil {
+		f.err = err
+		log.Printf("decompression of %s failed: %s", f.file.Name(), err)
+		return f
+	}
+	if c, ok := dr.(io.Closer); ok {
+		// decompressors are closed before the file
+		f.postread = append([]func(){func() { c.Close() }}, f.postread...)
+	}
+	f.count = &countingReader{r: dr}
+	f.buf = bufio.NewReader(f.count)
+	f.seek()
+	return f
+}
+
+// seek moves to the checkpointed offset. Uncompressed files are seeked,
+// anything else is read up to the offset and discarded.
+func (f *FileReader) seek() {
+	if f.resume == nil {
+		return
+	}
+	p, ok := f.resume.Progress(f.Name())
+	if !ok {
+		return
+	}
+	if p.Done {
+		f.base = p.Offset
+		f.err = io.EOF
+		return
+	}
+	if p.Offset == 0 {
+		return
+	}
+	if f.decompress == nil {
+		_, err := f.file.Seek(p.Offset, io.SeekStart)
+		if err == nil {
+			f.base = p.Offset
+			f.count = &countingReader{r: f.file}
+			f.buf = bufio.NewReader(f.count)
 			return
 		}
-		f.buf = bufio.NewReader(df)
-		f.ready = true
-	}()
+	}
+	_, err := io.CopyN(io.Discard, f.buf, p.Offset)
+	if err != nil {
+		f.err = fmt.Errorf("resuming %s at offset %v failed: %s", f.Name(), p.Offset, err)
+	}
+}
 
-	return f
+// Offset is how far into the decompressed input the records read so far
+// reach. CSVReader, JSONLReader and FrameReader have their own Offset,
+// for what they've read through this FileReader.
+func (f *FileReader) Offset() int64 {
+	if f.count == nil {
+		return f.base
+	}
+	return f.base + f.count.n - int64(f.buf.Buffered())
 }
 
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Read returns the next record with its delimiter. A last record without
+// one is returned as it is, io.EOF comes with the call after.
 func (f *FileReader) Read() ([]byte, error) {
-	if !f.ready {
-		f.wg.Wait()
+	if f.err != nil {
+		return nil, f.err
 	}
-	if f.empty {
-		return nil, fmt.Errorf("decompression failed")
+	b, err := f.buf.ReadBytes(f.delim)
+	if err == io.EOF && len(b) > 0 {
+		return b, nil
 	}
-	return f.buf.ReadBytes(f.delim)
+	return b, err
+}
+
+// source is the decompressed input, for RecordReaders that parse more than
+// delimited lines
+func (f *FileReader) source() (*bufio.Reader, error) {
+	return f.buf, f.err
 }
 
 func (f *FileReader) Close() {
@@ -108,3 +193,38 @@ func (f *FileReader) Close() {
 func (f *FileReader) Name() string {
 	return f.file.Name()
 }
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	bzip2Magic = []byte("BZh")
+	lzopMagic  = []byte{0x89, 'L', 'Z', 'O', 0x00, '\r', '\n', 0x1a, '\n'}
+)
+
+// detect picks a decompressor by magic bytes, falling back to the file
+// extension. nil means the file isn't compressed.
+func detect(br *bufio.Reader, name string) Decompressor {
+	magic, _ := br.Peek(len(lzopMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return GzipDecompressor
+	case bytes.HasPrefix(magic, zstdMagic):
+		return ZstdDecompressor
+	case bytes.HasPrefix(magic, bzip2Magic):
+		return Bzip2Decompressor
+	case bytes.HasPrefix(magic, lzopMagic):
+		return LzopDecompressor
+	}
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".gz", ".gzip":
+		return GzipDecompressor
+	case ".zst", ".zstd":
+		return ZstdDecompressor
+	case ".bz2":
+		return Bzip2Decompressor
+	case ".lzo":
+		return LzopDecompressor
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// RecordWriter is the sink side of RecordReader, it frames each record
+// as it's written. Close writes any trailer and flushes, it doesn't close
+// the underlying writer. Writers that buffer also have a Flush method,
+// which Write uses before a Checkpoint commits.
+type RecordWriter interface {
+	Write(record []byte) error
+	Close() error
+	Name() string
+}
+
+// RecordWriterFn builds a RecordWriter over the sink. Write calls it after
+// any compressor is in place.
+type RecordWriterFn func(w io.Writer) RecordWriter
+
+// Records sets how Write frames records, by default they're written raw
+func Records(fn RecordWriterFn) WriteOption {
+	return func(w *Write) *Write {
+		w.records = fn
+		return w
+	}
+}
+
+func RawRecords() RecordWriterFn {
+	return func(w io.Writer) RecordWriter {
+		return &rawWriter{w: w}
+	}
+}
+
+type rawWriter struct {
+	w io.Writer
+}
+
+func (r *rawWriter) Write(record []byte) error {
+	_, err := r.w.Write(record)
+	return err
+}
+
+func (r *rawWriter) Close() error {
+	return nil
+}
+
+func (r *rawWriter) Name() string {
+	return sinkName(r.w)
+}
+
+func DelimitedRecords(delim byte) RecordWriterFn {
+	return func(w io.Writer) RecordWriter {
+		return NewDelimitedWriter(w, delim)
+	}
+}
+
+// DelimitedWriter terminates each record with delim. Records that already
+// end in it, like those from FileReader, aren't terminated twice.
+type DelimitedWriter struct {
+	w     io.Writer
+	buf   *bufio.Writer
+	delim byte
+}
+
+func NewDelimitedWriter(w io.Writer, delim byte) *DelimitedWriter {
+	return &DelimitedWriter{
+		w:     w,
+		buf:   bufio.NewWriter(w),
+		delim: delim,
+	}
+}
+
+func (d *DelimitedWriter) Write(record []byte) error {
+	_, err := d.buf.Write(record)
+	if err != nil {
+		return err
+	}
+	if len(record) > 0 && record[len(record)-1] == d.delim {
+		return nil
+	}
+	return d.buf.WriteByte(d.delim)
+}
+
+func (d *DelimitedWriter) Flush() error {
+	return d.buf.Flush()
+}
+
+func (d *DelimitedWriter) Close() error {
+	return d.buf.Flush()
+}
+
+func (d *DelimitedWriter) Name() string {
+	return sinkName(d.w)
+}
+
+func sinkName(w io.Writer) string {
+	if n, ok := w.(interface{ Name() string }); ok {
+		return n.Name()
+	}
+	return fmt.Sprintf("%T", w)
+}
+
+// trimNewline drops a record's trailing line ending, for formats that
+// add their own
+func trimNewline(record []byte) []byte {
+	return bytes.TrimRight(record, "\r\n")
+}
@@ -0,0 +1,113 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeRecords runs a Write stage over records and returns its errors
+func writeRecords(w io.Writer, records []string, opts ...WriteOption) []error {
+	in := make(chan [][]byte, 1)
+	var chunk [][]byte
+	for _, rec := range records {
+		chunk = append(chunk, []byte(rec))
+	}
+	in <- chunk
+	close(in)
+	_, mon := NewWrite(context.Background(), w, []InChan{in}, opts...)
+	return monitorErrs(mon)
+}
+
+func TestRecordWriters(t *testing.T) {
+	tests := []struct {
+		name    string
+		records RecordWriterFn
+		in      []string
+		want    string
+		errs    int
+	}{
+		{"raw", RawRecords(), []string{"a", "b"}, "ab", 0},
+		{"delimited", DelimitedRecords('\n'), []string{"a", "b\n", ""}, "a\nb\n\n", 0},
+		{"csv", CSVRecords(CSVWriteHeader("name", "note")), []string{"a,\"comma, inside\"\n", "\"unterminated\n"}, "name,note\na,\"comma, inside\"\n", 1},
+		{"csv header only", CSVRecords(CRLF(), CSVWriteHeader("x")), nil, "x\r\n", 0},
+		{"json lines", JSONLRecords(), []string{"{\"a\":1}\n", "2", "{bad"}, "{\"a\":1}\n2\n", 1},
+		{"empty json array", JSONArrayRecords(), nil, "[]\n", 0},
+		{"json array", JSONArrayRecords(), []string{"1", "[2]\n", "bad"}, "[\n1,\n[2]\n]\n", 1},
+		{"fixed32", FrameRecords(Fixed32), []string{"hi"}, "\x00\x00\x00\x02hi", 0},
+		{"fixed32 little endian", FrameRecords(Fixed32, WriteLittleEndian()), []string{"hi"}, "\x02\x00\x00\x00hi", 0},
+		{"uvarint", FrameRecords(Uvarint), []string{"abc", ""}, "\x03abc\x00", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			errs := writeRecords(&out, tt.in, Records(tt.records))
+			assert.Len(t, errs, tt.errs, "wrong errors: %v", errs)
+			assert.Equal(t, tt.want, out.String(), "wrong output")
+		})
+	}
+}
+
+// what's written reads back the same
+func TestRecordWritersRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		records RecordWriterFn
+		reader  func(f *FileReader) RecordReader
+		in      []string
+	}{
+		{
+			"csv",
+			CSVRecords(CSVWriteHeader("name", "note")),
+			func(f *FileReader) RecordReader { return NewCSVReader(f, CSVHeader()) },
+			[]string{"a,\"comma, inside\"\n", "b,\"line one\nline two\"\n"},
+		},
+		{
+			"json lines",
+			JSONLRecords(),
+			func(f *FileReader) RecordReader { return NewJSONLReader(f) },
+			[]string{"{\"a\":1}\n", "[1,2]\n"},
+		},
+		{
+			"json array",
+			JSONArrayRecords(),
+			func(f *FileReader) RecordReader { return NewJSONArrayReader(f) },
+			[]string{"{\"a\":1}\n", "\"s\"\n", "null\n"},
+		},
+		{
+			"uvarint",
+			FrameRecords(Uvarint),
+			func(f *FileReader) RecordReader { return NewFrameReader(f, Uvarint) },
+			[]string{"abc", "", "with\nnewline"},
+		},
+		{
+			"fixed32 little endian",
+			FrameRecords(Fixed32, WriteLittleEndian()),
+			func(f *FileReader) RecordReader { return NewFrameReader(f, Fixed32, LittleEndian()) },
+			[]string{"abc", "", "with\nnewline"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out")
+			out, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			errs := writeRecords(out, tt.in, Records(tt.records))
+			out.Close()
+			assert.Empty(t, errs, "write failed")
+
+			r := tt.reader(openFile(t, path))
+			defer r.Close()
+			records, err := readAll(r)
+			assert.Nil(t, err, "read back failed")
+			assert.Equal(t, tt.in, records, "records didn't survive a round trip")
+		})
+	}
+}
@@ -4,49 +4,184 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type WriteOption func(c *Write) *Write
 
-// TODO: make record writer
+// WriteCheckpoint acknowledges each chunk to cp once it's written, see Checkpoint.
+// Before each commit the sink is flushed if it has a Flush method, and synced
+// if it's a file.
+func WriteCheckpoint(cp *Checkpoint) WriteOption {
+	return func(w *Write) *Write {
+		w.cp = cp
+		return w
+	}
+}
+
+// WritePolicy sets what happens to records the sink fails on, the default is Skip
+func WritePolicy(p ErrorPolicy) WriteOption {
+	return func(w *Write) *Write {
+		w.policy = p
+		return w
+	}
+}
+
 type Write struct {
-	w io.Writer
+	w  io.Writer
+	rw RecordWriter
+
+	// sink is the writer NewWrite was given, it's flushed and, if it's a
+	// regular file, synced before every checkpoint commit
+	sink io.Writer
+	sync *os.File
+
+	// records frames each record, it's built over the compressor if there is one
+	records RecordWriterFn
+
+	// compress wraps w, the compressor is closed after every input finishes
+	compress Compressor
+	closer   io.Closer
+	mu       sync.Mutex
+
+	failed int64
+	cp     *Checkpoint
+	policy ErrorPolicy
+	errs   *errorHandler
 
 	outgoing chan [][]byte
 
-	ctx     context.Context
-	donewg  sync.WaitGroup
-	monitor *Monitor
+	ctx       context.Context
+	cancelrep cancelReport
+	donewg    sync.WaitGroup
+	monitor   *Monitor
 }
 
-func NewWrite(w io.Writer, in []InChan, opts ...WriteOption) (*Write, *Monitor) {
-	ctx, canc := context.WithCancel(context.Background())
+func NewWrite(ctx context.Context, w io.Writer, in []InChan, opts ...WriteOption) (*Write, *Monitor) {
+	ctx, canc := context.WithCancel(ctx)
 
 	wr := &Write{
-		w:   w,
-		ctx: ctx,
+		w:       w,
+		sink:    w,
+		records: RawRecords(),
+		ctx:     ctx,
+	}
+	if f, ok := w.(*os.File); ok {
+		// fsync fails on pipes and terminals, like stdout often is
+		if info, err := f.Stat(); err == nil && info.Mode().IsRegular() {
+			wr.sync = f
+		}
 	}
 	for _, opt := range opts {
 		opt(wr)
 	}
 
-	wr.donewg.Add(len(in))
+	wr.donewg.Add(1)
 	monitor := NewMonitor(&wr.donewg, canc)
 
 	wr.monitor = monitor
+	wr.errs = newErrorHandler("writestream", wr.policy, func(err error) { monitor.SubmitErr(err) }, monitor.Fail)
 
-	for _, ch := range in {
-		go func() {
-			defer wr.donewg.Done()
-			wr.write(ch)
-		}()
+	if wr.compress != nil {
+		cw, err := wr.compress(wr.w)
+		if err != nil {
+			monitor.SubmitErr(fmt.Errorf("writestream: compressor failed: %s", err))
+			go func() {
+				for _, ch := range in {
+					for range ch {
+					}
+				}
+				wr.donewg.Done()
+			}()
+			return wr, monitor
+		}
+		wr.w = cw
+		wr.closer = cw
 	}
+	wr.rw = wr.records(wr.w)
+
+	// the monitor waits on donewg, so records are flushed and the compressor
+	// closed before anyone watching the monitor sees the write finish
+	go func() {
+		defer wr.donewg.Done()
+		var inputwg sync.WaitGroup
+		inputwg.Add(len(in))
+		for _, ch := range in {
+			go func(ch InChan) {
+				defer inputwg.Done()
+				wr.write(ch)
+			}(ch)
+		}
+		inputwg.Wait()
+		wr.close()
+		if atomic.LoadInt64(&wr.failed) == 0 && wr.errs.ok() && wr.ctx.Err() == nil {
+			wr.monitor.SetSuccess(true)
+		}
+	}()
 
 	return wr, monitor
 }
 
+func (w *Write) fail(err error) {
+	atomic.AddInt64(&w.failed, 1)
+	w.monitor.SubmitErr(err)
+}
+
+func (w *Write) close() {
+	err := w.rw.Close()
+	if err != nil {
+		w.fail(fmt.Errorf("writestream: flushing %s failed: %s", w.rw.Name(), err))
+	}
+	if w.closer != nil {
+		err = w.closer.Close()
+		if err != nil {
+			w.fail(fmt.Errorf("writestream: closing compressor failed: %s", err))
+		}
+	}
+	// everything is flushed, the last progress can be committed
+	if w.cp != nil && atomic.LoadInt64(&w.failed) == 0 {
+		err = w.cp.finish(w.flushSink)
+		if err != nil {
+			w.fail(fmt.Errorf("writestream: checkpoint failed: %s", err))
+		}
+	}
+}
+
+// flush pushes buffered records through the compressor to the sink, and
+// makes the sink durable
+func (w *Write) flush() error {
+	if f, ok := w.rw.(interface{ Flush() error }); ok {
+		err := f.Flush()
+		if err != nil {
+			return err
+		}
+	}
+	if f, ok := w.w.(interface{ Flush() error }); ok && w.closer != nil {
+		err := f.Flush()
+		if err != nil {
+			return err
+		}
+	}
+	return w.flushSink()
+}
+
+// flushSink flushes a buffered sink, like a *bufio.Writer, and syncs a file
+func (w *Write) flushSink() error {
+	if f, ok := w.sink.(interface{ Flush() error }); ok {
+		err := f.Flush()
+		if err != nil {
+			return err
+		}
+	}
+	if w.sync != nil {
+		return w.sync.Sync()
+	}
+	return nil
+}
+
 func (w *Write) write(in <-chan [][]byte) {
 	bcount := 0
 	mcount := 0
@@ -55,19 +190,28 @@ func (w *Write) write(in <-chan [][]byte) {
 	for chunk := range in {
 		select {
 		case <-w.ctx.Done():
-			w.monitor.SubmitErr(fmt.Errorf("writestream: canceled"))
+			w.cancelrep.submit(w.monitor, "writestream", w.ctx)
 			continue
 		default:
+			// inputs share the sink, chunks are written whole
+			w.mu.Lock()
 			for _, bytes := range chunk {
 				bcount += len(bytes)
 				mcount++
-				_, err := w.w.Write(bytes)
+				err := w.rw.Write(bytes)
+				if err != nil {
+					w.errs.failure(bytes, err)
+					continue
+				}
+				w.errs.success()
+			}
+			if w.cp != nil && !w.errs.stopped() {
+				err := w.cp.ack(w.flush)
 				if err != nil {
-					w.monitor.SubmitErr(err)
-					w.monitor.SubmitStat(fmt.Sprintf("write failed, completed write of %v bytes, %v messages in %s",
-						bcount, mcount, time.Since(start)))
+					w.fail(fmt.Errorf("writestream: checkpoint failed: %s", err))
 				}
 			}
+			w.mu.Unlock()
 		}
 	}
 	w.monitor.SubmitStat(fmt.Sprintf("successful write of %v bytes, %v messages in %s",